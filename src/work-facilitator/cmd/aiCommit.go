@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"spirit-dev/work-facilitator/work-facilitator/ai"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"spirit-dev/work-facilitator/work-facilitator/helper"
 
 	log "github.com/sirupsen/logrus"
@@ -154,7 +155,9 @@ func aiCommitCommand(cmd *cobra.Command, args []string) {
 	helper.RepoCommit(finalMessage, RootConfig.CommitIgnorePatternsCompiled)
 
 	// git push
+	var pushedCommits []c.CommitInfo
 	if !noPushAICommitArg {
+		pushedCommits = unpushedCommits(RootRepo.CurrentWorkflowData)
 		helper.SpinUpdateDisplay("Git push")
 		helper.RepoPush(RootRepo.PublicAuthKey, RootRepo.CurrentWorkflowData.Branch)
 	}
@@ -164,6 +167,7 @@ func aiCommitCommand(cmd *cobra.Command, args []string) {
 
 	if !noPushAICommitArg {
 		helper.SpinSideNoteDisplay("git push origin")
+		commentCommits(RootRepo.CurrentWorkflowData, pushedCommits)
 	}
 
 	// Say GoodBye
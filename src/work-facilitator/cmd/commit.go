@@ -6,6 +6,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"spirit-dev/work-facilitator/work-facilitator/helper"
 
 	log "github.com/sirupsen/logrus"
@@ -100,7 +101,9 @@ func commitCommand(cmd *cobra.Command, args []string) {
 	helper.RepoCommit(commitMessageCommit, RootConfig.CommitIgnorePatternsCompiled)
 
 	// git push
+	var pushedCommits []c.CommitInfo
 	if !noPushCommitArg {
+		pushedCommits = unpushedCommits(RootRepo.CurrentWorkflowData)
 		helper.SpinUpdateDisplay("Git push")
 		helper.RepoPush(RootRepo.PublicAuthKey, RootRepo.CurrentWorkflowData.Branch)
 	}
@@ -110,6 +113,7 @@ func commitCommand(cmd *cobra.Command, args []string) {
 
 	if !noPushCommitArg {
 		helper.SpinSideNoteDisplay("git push origin")
+		commentCommits(RootRepo.CurrentWorkflowData, pushedCommits)
 	}
 
 	// Say GoodBye
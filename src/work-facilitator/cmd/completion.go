@@ -102,22 +102,27 @@ func completionRunCommand(cmd *cobra.Command, args []string) {
 		helper.SpinSideNoteDisplay("file created " + fileName)
 	}
 
-	// Add alias
-	aliases := []string{
+	// Add alias, the commands opening or removing a worktree going through the function changing directory
+	run := "work-facilitator"
+	function := cdFunction(args[0])
+	if len(function) > 0 {
+		run = "_wf_cd"
+	}
+	aliases := append(function,
 		"",
 		"# Alias definition",
 		"alias wf='work-facilitator'",
 		"alias wfc='work-facilitator commit'",
 		"alias wfcai='work-facilitator ai-commit'",
-		"alias wfe='work-facilitator end'",
-		"alias wfi='work-facilitator init'",
-		"alias wfil='work-facilitator initLazy'",
+		"alias wfe='"+run+" end'",
+		"alias wfi='"+run+" init'",
+		"alias wfil='"+run+" initLazy'",
 		"alias wfl='work-facilitator list'",
 		"alias wfs='work-facilitator status'",
-		"alias wfu='work-facilitator use'",
+		"alias wfu='"+run+" use'",
 		"alias wfo='work-facilitator open'",
 		"alias wfp='work-facilitator pause'",
-	}
+	)
 	f, err := os.OpenFile(fileName, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
 	if err != nil {
 		log.Fatalln(err)
@@ -128,6 +133,43 @@ func completionRunCommand(cmd *cobra.Command, args []string) {
 	}
 }
 
+// cdFunction returns the shell function running work-facilitator with --cd-file,
+// then changing into the worktree the command opened (worktree mode)
+func cdFunction(shell string) []string {
+	switch shell {
+	case "bash", "zsh":
+		return []string{
+			"",
+			"# Change into the workflow worktree",
+			"_wf_cd() {",
+			"  local cd_file rc",
+			"  cd_file=\"$(mktemp)\"",
+			"  work-facilitator \"$@\" --cd-file \"$cd_file\"",
+			"  rc=$?",
+			"  if [ -s \"$cd_file\" ]; then cd \"$(cat \"$cd_file\")\" || rc=$?; fi",
+			"  rm -f \"$cd_file\"",
+			"  return $rc",
+			"}",
+		}
+	case "fish":
+		return []string{
+			"",
+			"# Change into the workflow worktree",
+			"function _wf_cd",
+			"    set -l cd_file (mktemp)",
+			"    work-facilitator $argv --cd-file $cd_file",
+			"    set -l rc $status",
+			"    if test -s $cd_file",
+			"        cd (cat $cd_file)",
+			"    end",
+			"    rm -f $cd_file",
+			"    return $rc",
+			"end",
+		}
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(completionCmd)
 }
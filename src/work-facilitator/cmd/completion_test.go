@@ -0,0 +1,70 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCdFunction(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	bin := t.TempDir()
+	worktree := t.TempDir()
+
+	// A fake work-facilitator writing the worktree into --cd-file for use only
+	fake := `#!/bin/sh
+while [ $# -gt 0 ]; do
+  if [ "$1" = "--cd-file" ]; then cd_file="$2"; fi
+  if [ "$1" = "use" ]; then use=1; fi
+  shift
+done
+if [ -n "$use" ]; then printf '%s' "` + worktree + `" > "$cd_file"; fi
+exit 3
+`
+	if err := os.WriteFile(filepath.Join(bin, "work-facilitator"), []byte(fake), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{
+			name:     "changes into the worktree",
+			command:  "_wf_cd use -w feat/x",
+			expected: worktree,
+		},
+		{
+			name:     "stays without worktree",
+			command:  "_wf_cd list",
+			expected: bin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := strings.Join(cdFunction("bash"), "\n") + "\ncd " + bin + "\n" + tt.command + "\necho \"$?:$PWD\"\n"
+			cmd := exec.Command("bash", "-c", script)
+			cmd.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"))
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("bash: %v\n%s", err, output)
+			}
+			if got := strings.TrimSpace(string(output)); got != "3:"+tt.expected {
+				t.Errorf("status and directory = %v, want 3:%v", got, tt.expected)
+			}
+		})
+	}
+
+	if cdFunction("powershell") != nil {
+		t.Error("cdFunction(powershell) expected no function")
+	}
+}
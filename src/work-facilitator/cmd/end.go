@@ -7,6 +7,9 @@ import (
 	"os"
 	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"spirit-dev/work-facilitator/work-facilitator/ticketing"
+	"strconv"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -15,12 +18,17 @@ import (
 
 var (
 	// cmd Args
-	workEndArg string
-	forceEnd   bool
+	workEndArg       string
+	forceEnd         bool
+	noTransitionEnd  bool
+	worklogDryRunEnd bool
 
 	// local variables
 	workToDeleteEnd string
 	currentEnd      bool
+	mergeEnd        ticketing.MergeState
+	worktreeEnd     string
+	mainPathEnd     string
 )
 
 // endCmd represents the end command
@@ -74,8 +82,27 @@ func endPreRunCommand(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	// Ensure the merge request of the workflow is merged
+	if !forceEnd {
+		mergeEnd = checkMergeState(helper.RepoConfigGetCurrentWorkflow(workToDeleteEnd))
+	}
+
+	// A workflow worktree is only removed by git without uncommitted files, unless forced
+	if RootConfig.WorktreeEnabled {
+		mainPath, worktrees, err := helper.RepoWorktrees()
+		if err != nil {
+			helper.SpinStopDisplay("fail")
+			log.Fatalln(err)
+		}
+		if path := worktrees[workToDeleteEnd]; path != "" && path != mainPath {
+			worktreeEnd, mainPathEnd = path, mainPath
+		}
+	}
+
 	// Check for uncommitted files during validation phase
-	if RootConfig.UncommittedFilesDetection != "disabled" && !forceEnd {
+	if worktreeEnd != "" {
+		log.Debug("Worktree " + worktreeEnd + ", uncommitted files checked on removal")
+	} else if RootConfig.UncommittedFilesDetection != "disabled" && !forceEnd {
 		log.Debug("Checking for uncommitted files...")
 		uncommittedFiles, hasUncommitted, err := helper.RepoCheckUncommittedFiles()
 		if err != nil {
@@ -117,7 +144,7 @@ func endCommand(cmd *cobra.Command, args []string) {
 	helper.SpinStartDisplay("Git operations")
 
 	// Re-check for uncommitted files in case files changed between PreRun and Run
-	if RootConfig.UncommittedFilesDetection == "fatal" && !forceEnd {
+	if RootConfig.UncommittedFilesDetection == "fatal" && !forceEnd && worktreeEnd == "" {
 		log.Debug("Re-checking for uncommitted files before git operations...")
 		uncommittedFiles, hasUncommitted, err := helper.RepoCheckUncommittedFiles()
 		if err != nil {
@@ -139,12 +166,24 @@ func endCommand(cmd *cobra.Command, args []string) {
 	}
 	log.Debugf("refBranch: %v\n", refBranch)
 
+	// Keep the workflow data before its config is cleaned up
+	workflowEnd := helper.RepoConfigGetCurrentWorkflow(workToDeleteEnd)
+
 	// Get branch ref (plumbing)
 	workToDeleteRef := helper.RepoGetBranchRef(workToDeleteEnd)
 
-	// execute checkout and pull only if we are in the current worflow
+	// remove the workflow worktree, or execute checkout and pull only if we are in the current worflow
 	pullInfo := ""
-	if currentEnd {
+	if worktreeEnd != "" {
+		helper.SpinUpdateDisplay("git worktree remove " + worktreeEnd)
+		if err := helper.RepoWorktreeRemove(worktreeEnd, forceEnd); err != nil {
+			helper.SpinStopDisplay("fail")
+			log.Warningln(err)
+			log.Warningln("Commit the changes of " + worktreeEnd + ", or discard them with:")
+			log.Warningln("#> " + RootConfig.ScriptName + " end -w " + workToDeleteEnd + " --force")
+			os.Exit(1)
+		}
+	} else if currentEnd {
 		helper.SpinUpdateDisplay("git checkout " + refBranch)
 		helper.RepoCheckout(refBranch, RootRepo.PublicAuthKey)
 
@@ -156,10 +195,24 @@ func endCommand(cmd *cobra.Command, args []string) {
 	helper.SpinUpdateDisplay("git branch -D " + workToDeleteEnd)
 	helper.RepoDeleteBranch(workToDeleteEnd, workToDeleteRef)
 
+	// Delete the remote branch once merged
+	remoteDeleted := false
+	if mergeEnd.Merged {
+		helper.SpinUpdateDisplay("git push origin --delete " + workToDeleteEnd)
+		if errR := helper.RepoDeleteRemoteBranch(RootRepo.PublicAuthKey, workToDeleteEnd); errR != nil {
+			log.Warningln("Remote branch not deleted: " + errR.Error())
+		} else {
+			remoteDeleted = true
+		}
+	}
+
+	// Close the work interval before the workflow config is cleaned up
+	pending, since := helper.RepoConfigStopWork(workToDeleteEnd, time.Now())
+
 	// Cleanup workflow config
 	helper.RepoConfigDeleteWorkflow(workToDeleteEnd)
 	helper.RepoConfigDeleteBranch(workToDeleteEnd)
-	if currentEnd {
+	if currentEnd || helper.RepoConfigGetCurrentWorkflowName() == workToDeleteEnd {
 		// Delete current workflow
 		helper.RepoConfigDeleteCurrentWorkflow()
 	}
@@ -168,15 +221,62 @@ func endCommand(cmd *cobra.Command, args []string) {
 
 	helper.SpinUpdateDisplay("Git operations")
 	helper.SpinStopDisplay("success")
+	if worktreeEnd != "" {
+		helper.SpinSideNoteDisplay("Worktree removed > " + worktreeEnd)
+	}
 	helper.SpinSideNoteDisplay("Branch deleted > " + workToDeleteEnd)
+	if remoteDeleted {
+		helper.SpinSideNoteDisplay("Remote branch deleted > origin/" + workToDeleteEnd)
+	}
+	if mergeEnd.MergeCommit != "" {
+		helper.SpinSideNoteDisplay("Merge commit > " + mergeEnd.MergeCommit)
+	}
 	if pullInfo != "" {
 		helper.SpinSideNoteDisplay("Pull info: " + pullInfo)
 	}
 
+	tracker := optionalTracker()
+	if !noTransitionEnd {
+		transitionTicket(tracker, workflowEnd, c.EventEnd)
+	}
+	logWork(tracker, workflowEnd, pending, since, worklogDryRunEnd)
+	if worktreeEnd != "" && currentEnd {
+		// The shell was in the removed worktree
+		cdInto(mainPathEnd)
+	}
+
 	// Say GoodBye
 	helper.ByeByeDisplay()
 }
 
+// checkMergeState looks up the merge request of the workflow and asks for confirmation
+// when it is not merged. Workflows without merge request, or whose tracker cannot tell, are not checked.
+func checkMergeState(wf c.Workflow) ticketing.MergeState {
+	checker, ok := optionalTracker().(ticketing.MergeChecker)
+	if !ok || wf.Issue == 0 {
+		return ticketing.MergeState{}
+	}
+
+	helper.SpinUpdateDisplay("Verifications - merge request !" + strconv.Itoa(wf.Issue))
+	state, err := checker.MergeState(wf.Issue)
+	if err == nil && state.Merged {
+		return state
+	}
+
+	helper.SpinStopDisplay("warning")
+	if err != nil {
+		log.Warningln("Merge request state unknown: " + err.Error())
+	} else {
+		log.Warningln("Merge request !" + strconv.Itoa(wf.Issue) + " is " + state.State + ", not merged")
+	}
+	if !helper.PromptUserConfirmation("End the workflow anyway?") {
+		log.Fatalln("Operation cancelled by user, use --force to skip the merge check")
+	}
+	helper.SpinStartDisplay("Verifications - end...")
+
+	return state
+}
+
 func init() {
 	rootCmd.AddCommand(endCmd)
 
@@ -191,5 +291,7 @@ func init() {
 	}
 
 	endCmd.Flags().StringVarP(&workEndArg, "work", "w", c.NOTGIVEN, "Work to end \n"+worklistStr)
-	endCmd.Flags().BoolVarP(&forceEnd, "force", "f", false, "Force end workflow, skip uncommitted files check")
+	endCmd.Flags().BoolVarP(&forceEnd, "force", "f", false, "Force end workflow, skip uncommitted files and merge request checks")
+	endCmd.Flags().BoolVar(&noTransitionEnd, "no-transition", false, "Do not transition the ticket")
+	endCmd.Flags().BoolVar(&worklogDryRunEnd, "worklog-dry-run", false, "Preview the worklog without posting it")
 }
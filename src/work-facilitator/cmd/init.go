@@ -8,7 +8,8 @@ import (
 	"os"
 	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"spirit-dev/work-facilitator/work-facilitator/helper"
-	"strconv"
+	"spirit-dev/work-facilitator/work-facilitator/ticketing"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,10 +25,21 @@ var (
 	issueInitArg          int
 	ticketInitArg         string
 	titleSeparatorInitArg string
+	noTransitionInitArg   bool
+	createMrInitArg       bool
+	codeOwnersInitArg     bool
+	createTicketInitArg   bool
+	projectInitArg        string
+	ticketTypeInitArg     string
+	editInitArg           bool
 
 	// local variables
-	currentWorkInit string
-	commitInit      string
+	currentWorkInit   string
+	commitInit        string
+	linkedIssueInit   int
+	descriptionInit   string
+	createdTicketInit bool
+	issueTitleInit    string
 
 	initArgs = []string{
 		"message\tCommit message",
@@ -38,8 +50,8 @@ var (
 var initCmd = &cobra.Command{
 	Use:       "init issue title branch_type" + RootConfig.BranchContentStr + " [flags]",
 	Short:     "initialize workflow",
-	Long:      `Start a new workflow`,
-	Args:      cobra.ExactArgs(3),
+	Long:      "Start a new workflow\n\nWith --create-ticket or --create-mr, the ticket or the merge request is created from the title and the issue is left out: init title branch_type --create-ticket",
+	Args:      initArgsCount,
 	ValidArgs: initArgs,
 	PreRun:    initPreRunCommand,
 	Run:       initCommand,
@@ -50,23 +62,40 @@ func initPreRunCommand(cmd *cobra.Command, args []string) {
 	RootConfig = helper.NewConfig()
 	RootRepo = helper.NewRepo(RootConfig)
 
+	// The description is written before the spinner starts, the editor taking over the terminal
+	if createTicketInitArg && editInitArg {
+		descriptionInit = editMessage("")
+	}
+
 	helper.SpinStartDisplay("Verifications...")
-	// Extract issue or ticket depending on GitLab or Jira
-	// For Gitlab, we expect an int
-	// For Jira, we expect a string
-	if RootConfig.Ticketing == c.GITLAB {
-		issueG, errG := strconv.Atoi(args[0])
-		if errG != nil {
-			log.Warningln("The issue should be an integer, corresponding to a GitLab MR number")
-		}
-		issueInitArg = issueG
+	RootTracker = newRootTracker()
+
+	if _, ok := RootTracker.(ticketing.MergeRequester); createMrInitArg && !ok {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln("--create-mr is not supported by the " + RootTracker.Name() + " ticketing")
+	}
+	creator, ok := RootTracker.(ticketing.Creator)
+	if createTicketInitArg && !ok {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln("--create-ticket is not supported by the " + RootTracker.Name() + " ticketing")
 	}
-	if RootConfig.Ticketing == c.JIRA {
-		ticketInitArg = args[0]
+
+	// Extract issue or ticket depending on the ticketing system
+	// A merge request or a ticket created by init does not exist yet, no issue is then given
+	var ticket ticketing.Ticket
+	if !createTicketInitArg && !createMrInitArg {
+		var errT error
+		if ticket, errT = RootTracker.ParseKey(args[0]); errT != nil {
+			log.Warningln(errT)
+		}
 	}
+	issueInitArg = ticket.ID
+	ticketInitArg = ticket.Key
 
-	titleInitArg = args[1]
-	branchTypeInitArg = args[2]
+	// The title and the branch type follow the issue, when given
+	titleIndex := len(args) - 2
+	titleInitArg = args[titleIndex]
+	branchTypeInitArg = args[titleIndex+1]
 
 	// Set default ref branch
 	if refBranchInitArg == c.NOTGIVENBRANCH {
@@ -92,25 +121,35 @@ func initPreRunCommand(cmd *cobra.Command, args []string) {
 	if commitTypeInitArg == c.NOTGIVEN {
 		commitTypeInitArg = helper.DefineCommit(branchTypeInitArg, RootConfig.TypeMapping)
 	}
-	// Prepare variables depending on ticketing service
-	if RootConfig.Ticketing == c.GITLAB {
-		currentWorkInit = titleInitArg
-		commitInit = fmt.Sprintf("%s(!%d): ", commitTypeInitArg, issueInitArg)
-	}
-	if RootConfig.Ticketing == c.JIRA {
-		// Define branch template
-		currentWorkInit = helper.Template(RootConfig.BranchTemplate, map[string]interface{}{
-			"type":    branchTypeInitArg,
-			"issue":   ticketInitArg,
-			"summary": titleInitArg,
-		})
-		// Define commit template
-		commitInit = helper.Template(RootConfig.CommitTemplate, map[string]interface{}{
-			"type":  commitTypeInitArg,
-			"issue": ticketInitArg,
+	// Create the ticket, the workflow then starts from it
+	if createTicketInitArg {
+		helper.SpinUpdateDisplay("Ticket creation")
+		project := projectInitArg
+		if project == "" {
+			project = RootConfig.TicketingJiraProject
+		}
+		created, err := creator.CreateTicket(ticketing.NewTicket{
+			Project:     project,
+			Type:        ticketTypeInitArg,
+			Summary:     args[titleIndex],
+			Description: descriptionInit,
 		})
+		if err != nil {
+			helper.SpinStopDisplay("fail")
+			log.Fatalln(err)
+		}
+		ticket = created
+		issueInitArg = ticket.ID
+		ticketInitArg = ticket.Key
+		linkedIssueInit = ticket.IssueID
+		issueTitleInit = ticket.Title
+		createdTicketInit = true
 	}
 
+	// Prepare variables depending on ticketing service
+	ticket.Title = titleInitArg
+	currentWorkInit, commitInit = RootTracker.WorkflowContext(RootConfig, ticket, branchTypeInitArg, commitTypeInitArg)
+
 	// Ensure standard is correct (if enforced)
 	if !helper.TestStandard(commitInit, RootConfig.CommitExpr, currentWorkInit, RootConfig.BranchExpr, RootConfig.EnforceStandard) {
 		helper.SpinStopDisplay("fail")
@@ -128,6 +167,18 @@ func initPreRunCommand(cmd *cobra.Command, args []string) {
 
 	helper.SpinUpdateDisplay("Verifications")
 	helper.SpinStopDisplay("success")
+	if createdTicketInit {
+		helper.SpinSideNoteDisplay("Ticket created: " + ticketInitArg)
+	}
+}
+
+// initArgsCount expects the issue, the title and the branch type,
+// without issue when --create-ticket or --create-mr creates it
+func initArgsCount(cmd *cobra.Command, args []string) error {
+	if createTicketInitArg || createMrInitArg {
+		return cobra.ExactArgs(2)(cmd, args)
+	}
+	return cobra.ExactArgs(3)(cmd, args)
 }
 
 func initCommand(cmd *cobra.Command, args []string) {
@@ -138,6 +189,7 @@ func initCommand(cmd *cobra.Command, args []string) {
 		BranchType:  branchTypeInitArg,
 		CommitType:  commitTypeInitArg,
 		Issue:       issueInitArg,
+		LinkedIssue: linkedIssueInit,
 		Ticket:      ticketInitArg,
 		Title:       titleInitArg,
 		Commit:      commitInit,
@@ -145,24 +197,56 @@ func initCommand(cmd *cobra.Command, args []string) {
 		Branch:      currentWorkInit,
 	}
 	// Set the current worklow
-	helper.RepoConfigDefineWorkflow(RootConfig, workflow)
+	helper.RepoConfigDefineWorkflow(workflow, RootTracker.Refs(workflow))
+	helper.RepoConfigStartWork(currentWorkInit, time.Now())
+
+	// execute git actions, in a worktree of its own in worktree mode
+	pullInfo := ""
+	worktree := ""
+	if RootConfig.WorktreeEnabled {
+		worktree, _ = openWorktree(currentWorkInit, refBranchInitArg)
+	} else {
+		helper.SpinUpdateDisplay("git checkout")
+		helper.RepoCheckout(refBranchInitArg, RootRepo.PublicAuthKey)
+		helper.SpinUpdateDisplay("git pull")
+		pullInfo = helper.RepoPull(RootRepo.PublicAuthKey)
+		helper.SpinUpdateDisplay("git checkout")
+		helper.RepoCheckout(currentWorkInit, RootRepo.PublicAuthKey)
+	}
 
-	// execute git actions
-	helper.SpinUpdateDisplay("git checkout")
-	helper.RepoCheckout(refBranchInitArg, RootRepo.PublicAuthKey)
-	helper.SpinUpdateDisplay("git pull")
-	pullInfo := helper.RepoPull(RootRepo.PublicAuthKey)
-	helper.SpinUpdateDisplay("git checkout")
-	helper.RepoCheckout(currentWorkInit, RootRepo.PublicAuthKey)
+	if createMrInitArg {
+		mr := ticketing.MergeRequest{
+			Title:     mergeRequestTitle(workflow),
+			Reviewers: codeOwnersReviewers(workflow, codeOwnersInitArg),
+		}
+		if linkedIssueInit != 0 {
+			mr.Title = fmt.Sprintf("Resolve #%d \"%s\"", linkedIssueInit, issueTitleInit)
+			mr.Description = fmt.Sprintf("Closes #%d", linkedIssueInit)
+		}
+		workflow = createMergeRequest(workflow, mr)
+	} else {
+		workflow = linkMergeRequest(workflow)
+	}
 
 	// Write workflow
 	helper.RepoConfigWrite()
 	helper.SpinUpdateDisplay("Git operations")
 	helper.SpinStopDisplay("success")
 
-	helper.SpinSideNoteDisplay("Pull info: " + pullInfo)
+	if worktree != "" {
+		helper.SpinSideNoteDisplay("Worktree > " + worktree)
+	} else {
+		helper.SpinSideNoteDisplay("Pull info: " + pullInfo)
+	}
 
-	helper.ShowSummary(RootConfig, workflow)
+	if !noTransitionInitArg {
+		transitionTicket(RootTracker, workflow, c.EventInit)
+	}
+
+	helper.ShowSummary(workflow, RootTracker.Refs(workflow))
+	if worktree != "" {
+		cdInto(worktree)
+	}
 
 	// Say GoodBye
 	helper.ByeByeDisplay()
@@ -174,6 +258,13 @@ func init() {
 	initCmd.Flags().StringVarP(&commitTypeInitArg, "commit-type", "c", c.NOTGIVEN, "Specify the commit type to be treated "+RootConfig.CommitTypeStr)
 	initCmd.Flags().StringVarP(&refBranchInitArg, "ref-branch", "r", c.NOTGIVENBRANCH, "Specify the source branch")
 	initCmd.Flags().StringVarP(&titleSeparatorInitArg, "separator", "s", c.NOTGIVEN, "Specify the separator in the branch title")
+	initCmd.Flags().BoolVar(&noTransitionInitArg, "no-transition", false, "Do not transition the ticket")
+	initCmd.Flags().BoolVar(&createMrInitArg, "create-mr", false, "Push the branch and create a draft merge request, no issue argument is given")
+	initCmd.Flags().BoolVar(&codeOwnersInitArg, "codeowners", false, "Request a review from the CODEOWNERS when creating the merge request")
+	initCmd.Flags().BoolVar(&createTicketInitArg, "create-ticket", false, "Create the ticket (Jira or GitLab issue) from the title, no issue argument is given")
+	initCmd.Flags().StringVar(&projectInitArg, "project", "", "Jira project of the created ticket, defaults to ticketing.jira.project")
+	initCmd.Flags().StringVar(&ticketTypeInitArg, "ticket-type", "", "Jira issue type of the created ticket (default Task)")
+	initCmd.Flags().BoolVarP(&editInitArg, "edit", "e", false, "Write the description of the created ticket in $EDITOR")
 
 	initCmd.MarkFlagRequired("title")
 	initCmd.MarkFlagRequired("branch-type")
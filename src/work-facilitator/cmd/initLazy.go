@@ -9,7 +9,7 @@ import (
 	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"spirit-dev/work-facilitator/work-facilitator/helper"
 	"spirit-dev/work-facilitator/work-facilitator/ticketing"
-	"strconv"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -23,24 +23,32 @@ var (
 	commitTypeInitLArg     string
 	refBranchInitLArg      string
 	titleSeparatorInitLArg string
+	noTransitionInitLArg   bool
+	glabIssueInitLArg      bool
+	createMrInitLArg       bool
+	codeOwnersInitLArg     bool
+	refreshInitLArg        bool
 
 	// local variables
 	currentWorkInitL string
 	commitInitL      string
 	summaryInitL     string
+	linkedIssueInitL int
+	ticketInitL      string
+	issueTitleInitL  string
 
 	initLazyArgs = []string{
 		"issue\tIssue from GitLab or Jira",
-		"branch_type\tBranch type ",
+		"branch_type\tBranch type, inferred from the ticket when omitted",
 	}
 )
 
 // initLazyCmd represents the initLazy command
 var initLazyCmd = &cobra.Command{
-	Use:       "initLazy issue branch_type" + RootConfig.BranchContentStr + " [flags]",
+	Use:       "initLazy issue [branch_type" + RootConfig.BranchContentStr + "] [flags]",
 	Short:     "initialize workflow lazy style",
 	Long:      "Start a new workflow lazy style",
-	Args:      cobra.ExactArgs(2),
+	Args:      cobra.RangeArgs(1, 2),
 	ValidArgs: initLazyArgs,
 	PreRun:    initLazyPreRunCommand,
 	Run:       initLazyCommand,
@@ -52,6 +60,16 @@ func initLazyPreRunCommand(cmd *cobra.Command, args []string) {
 	RootRepo = helper.NewRepo(RootConfig)
 
 	log.Debug("pre run init-lazy")
+	branchType := ""
+	if len(args) > 1 {
+		branchType = args[1]
+	}
+	initLazyPrepare(args[0], branchType)
+}
+
+// initLazyPrepare fetches the ticket and prepares the workflow to start from it.
+// An empty branch type is inferred from the ticket type or labels.
+func initLazyPrepare(issue, branchType string) {
 	helper.SpinStartDisplay("Verifications - init-lazy...")
 
 	if RootRepo.HasCurrentWorkflow {
@@ -59,17 +77,8 @@ func initLazyPreRunCommand(cmd *cobra.Command, args []string) {
 		log.Fatalln("You are in a workflow at this moment. Weird behaviour might occur")
 	}
 
-	issueInitLArg = args[0]                         // Sets for either Glab or Jira
-	issueInitLArgI, _ = strconv.Atoi(issueInitLArg) // Set for gitlab with an attempt to str to int. Fails for Jira
-	log.Debugf("issueInitLArg: %v\n", issueInitLArg)
-	log.Debugf("issueInitLArgI: %v\n", issueInitLArgI)
-
-	branchTypeInitLArg = args[1]
-
-	// Override commit type if not given
-	if commitTypeInitLArg == c.NOTGIVEN {
-		commitTypeInitLArg = helper.DefineCommit(branchTypeInitLArg, RootConfig.TypeMapping)
-	}
+	issueInitLArg = issue
+	branchTypeInitLArg = branchType
 
 	// Define separator
 	// Precedence:
@@ -83,49 +92,45 @@ func initLazyPreRunCommand(cmd *cobra.Command, args []string) {
 		titleSeparatorInitLArg = RootRepo.Separator
 	}
 
-	if RootConfig.Ticketing == c.JIRA {
-		// Prepare Jira
-		ticketing.ClientJira(c.JiraConfig{
-			Server:   RootConfig.TicketingJiraServer,
-			Username: RootConfig.TicketingJiraUsername,
-			Password: RootConfig.TicketingJiraPassword,
-		})
-		// get Jira issue
-		issue := ticketing.GetJiraIssue(issueInitLArg)
-		// Build summary
-		summaryInitL = helper.CleanString(issue.Fields.Summary, titleSeparatorInitLArg)
-		log.Debugf("summaryInitL: %v\n", summaryInitL)
-
-		// Define branch template
-		currentWorkInitL = helper.Template(RootConfig.BranchTemplate, map[string]interface{}{
-			"type":    branchTypeInitLArg,
-			"issue":   issueInitLArg,
-			"summary": summaryInitL,
-		})
-		// Define commit template
-		commitInitL = helper.Template(RootConfig.CommitTemplate, map[string]interface{}{
-			"type":  commitTypeInitLArg,
-			"issue": issueInitLArg,
-		})
+	// Get the ticket from the ticketing system
+	if RootTracker == nil {
+		RootTracker = newRootTracker()
+	}
+	ticket, err := fetchInitLazyTicket(issueInitLArg)
+	if err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+	issueInitLArgI = ticket.ID
+	linkedIssueInitL = ticket.IssueID
+	// The key as normalised by the tracker (`AB#1234` gives `1234`), transitions use it
+	ticketInitL = ticket.Key
+
+	// Infer the branch type from the ticket when not given
+	branchTypeRule := ""
+	if branchTypeInitLArg == "" {
+		branchTypeInitLArg, branchTypeRule = helper.DefineBranchType(ticket.Type, ticket.Labels, RootConfig.BranchTypeMapping)
+		if branchTypeInitLArg == "" {
+			helper.SpinStopDisplay("fail")
+			log.Fatalln("No branch type given, and none inferred from the ticket type or labels (see global.branch_type_mapping)")
+		}
 	}
 
-	if RootConfig.Ticketing == c.GITLAB {
-
-		// Prepare gitlab
-		ticketing.ClientGlab(c.GlabConfig{
-			BaseUrl: RootConfig.TicketingGlabServer,
-			Token:   RootConfig.TicketingGlabToken,
-		})
-		// Get Gitlab issue
-		issue := ticketing.GetGlabIssue(issueInitLArgI, RootRepo.FName)
-		summaryInitL = helper.CleanString(helper.CleanGlabString(issue.Title), titleSeparatorInitLArg)
-		log.Debugf("issue.Title: `%v` --> `%v`\n", issue.Title, summaryInitL)
+	// Override commit type if not given
+	if commitTypeInitLArg == c.NOTGIVEN {
+		commitTypeInitLArg = helper.DefineCommit(branchTypeInitLArg, RootConfig.TypeMapping)
+	}
+	log.Debugf("issueInitLArg: %v\n", issueInitLArg)
+	log.Debugf("issueInitLArgI: %v\n", issueInitLArgI)
 
-		currentWorkInitL = issue.SourceBranch
+	// Build summary, the merge request resolving an issue keeping the issue title as fetched
+	issueTitleInitL = ticket.Title
+	summaryInitL = helper.CleanString(ticket.Title, titleSeparatorInitLArg)
+	log.Debugf("summaryInitL: %v\n", summaryInitL)
 
-		// Define commit pre message
-		commitInitL = fmt.Sprintf("%s(!%s): ", commitTypeInitLArg, issueInitLArg)
-	}
+	// Define branch and commit pre message
+	ticket.Title = summaryInitL
+	currentWorkInitL, commitInitL = RootTracker.WorkflowContext(RootConfig, ticket, branchTypeInitLArg, commitTypeInitLArg)
 
 	// Set default ref branch
 	if refBranchInitLArg == c.NOTGIVENBRANCH {
@@ -150,11 +155,9 @@ func initLazyPreRunCommand(cmd *cobra.Command, args []string) {
 	helper.SpinUpdateDisplay("Verifications")
 	helper.SpinStopDisplay("success")
 
-	if RootConfig.Ticketing == c.JIRA {
-		helper.SpinSideNoteDisplay("Got jira issue")
-	}
-	if RootConfig.Ticketing == c.GITLAB {
-		helper.SpinSideNoteDisplay("Got GitLab mr")
+	helper.SpinSideNoteDisplay("Got " + RootTracker.Name() + " ticket " + issueInitLArg)
+	if branchTypeRule != "" {
+		helper.SpinSideNoteDisplay("Branch type inferred from " + branchTypeRule)
 	}
 }
 
@@ -167,41 +170,103 @@ func initLazyCommand(cmd *cobra.Command, args []string) {
 		BranchType:  branchTypeInitLArg,
 		CommitType:  commitTypeInitLArg,
 		Issue:       issueInitLArgI,
-		Ticket:      issueInitLArg,
+		LinkedIssue: linkedIssueInitL,
+		Ticket:      ticketInitL,
 		Title:       summaryInitL,
 		Commit:      commitInitL,
 		RefBranch:   refBranchInitLArg,
 		Branch:      currentWorkInitL,
 	}
 	// Set the current worklow
-	helper.RepoConfigDefineWorkflow(RootConfig, workflow)
+	helper.RepoConfigDefineWorkflow(workflow, RootTracker.Refs(workflow))
+	helper.RepoConfigStartWork(currentWorkInitL, time.Now())
+
+	// execute git actions, in a worktree of its own in worktree mode
+	pullInfo := ""
+	worktree := ""
+	if RootConfig.WorktreeEnabled {
+		worktree, _ = openWorktree(currentWorkInitL, refBranchInitLArg)
+	} else {
+		helper.SpinUpdateDisplay("git checkout")
+		helper.RepoCheckout(refBranchInitLArg, RootRepo.PublicAuthKey)
+		helper.SpinUpdateDisplay("git pull")
+		pullInfo = helper.RepoPull(RootRepo.PublicAuthKey)
+		helper.SpinUpdateDisplay("git checkout")
+		helper.RepoCheckout(currentWorkInitL, RootRepo.PublicAuthKey)
+	}
 
-	// execute git actions
-	helper.SpinUpdateDisplay("git checkout")
-	helper.RepoCheckout(refBranchInitLArg, RootRepo.PublicAuthKey)
-	helper.SpinUpdateDisplay("git pull")
-	pullInfo := helper.RepoPull(RootRepo.PublicAuthKey)
-	helper.SpinUpdateDisplay("git checkout")
-	helper.RepoCheckout(currentWorkInitL, RootRepo.PublicAuthKey)
+	if createMrInitLArg && linkedIssueInitL != 0 {
+		workflow = createMergeRequest(workflow, ticketing.MergeRequest{
+			Title:       fmt.Sprintf("Resolve #%d \"%s\"", linkedIssueInitL, issueTitleInitL),
+			Description: fmt.Sprintf("Closes #%d", linkedIssueInitL),
+			Reviewers:   codeOwnersReviewers(workflow, codeOwnersInitLArg),
+		})
+	} else if createMrInitLArg {
+		workflow = createMergeRequest(workflow, ticketing.MergeRequest{
+			Title:     mergeRequestTitle(workflow),
+			Reviewers: codeOwnersReviewers(workflow, codeOwnersInitLArg),
+		})
+	} else {
+		workflow = linkMergeRequest(workflow)
+	}
 
 	// Write workflow
 	helper.RepoConfigWrite()
 	helper.SpinUpdateDisplay("Git operations")
 	helper.SpinStopDisplay("success")
-	helper.SpinSideNoteDisplay("Pull info: " + pullInfo)
+	if worktree != "" {
+		helper.SpinSideNoteDisplay("Worktree > " + worktree)
+	} else {
+		helper.SpinSideNoteDisplay("Pull info: " + pullInfo)
+	}
 
-	helper.ShowSummary(RootConfig, workflow)
+	if !noTransitionInitLArg {
+		transitionTicket(RootTracker, workflow, c.EventInit)
+	}
+
+	helper.ShowSummary(workflow, RootTracker.Refs(workflow))
+	if worktree != "" {
+		cdInto(worktree)
+	}
 
 	// Say GoodBye
 	helper.ByeByeDisplay()
 }
 
+// fetchInitLazyTicket retrieves the ticket the workflow starts from,
+// a tracker issue instead of a merge request when --issue is given
+func fetchInitLazyTicket(key string) (ticketing.Ticket, error) {
+	if _, ok := RootTracker.(ticketing.MergeRequester); createMrInitLArg && !ok {
+		return ticketing.Ticket{}, fmt.Errorf("--create-mr is not supported by the %s ticketing", RootTracker.Name())
+	}
+
+	if !glabIssueInitLArg {
+		ticket, err := fetchCached(RootTracker, "ticket", key, refreshInitLArg, func() (ticketing.Ticket, error) { return RootTracker.FetchTicket(key) })
+		if err == nil && createMrInitLArg && ticket.ID != 0 {
+			return ticket, fmt.Errorf("--create-mr: the merge request !%d already exists, use --issue to start from an issue", ticket.ID)
+		}
+		return ticket, err
+	}
+
+	fetcher, ok := RootTracker.(ticketing.IssueFetcher)
+	if !ok {
+		return ticketing.Ticket{}, fmt.Errorf("--issue is not supported by the %s ticketing", RootTracker.Name())
+	}
+
+	return fetchCached(RootTracker, "issue", key, refreshInitLArg, func() (ticketing.Ticket, error) { return fetcher.FetchIssue(key) })
+}
+
 func init() {
 	rootCmd.AddCommand(initLazyCmd)
 
 	initLazyCmd.Flags().StringVarP(&commitTypeInitLArg, "commit-type", "c", c.NOTGIVEN, "Specify the commit type to be treated "+RootConfig.CommitTypeStr)
 	initLazyCmd.Flags().StringVarP(&refBranchInitLArg, "ref-branch", "r", c.NOTGIVENBRANCH, "Specify the source branch")
 	initLazyCmd.Flags().StringVarP(&titleSeparatorInitLArg, "separator", "s", c.NOTGIVEN, "Specify the separator in the branch title")
+	initLazyCmd.Flags().BoolVar(&noTransitionInitLArg, "no-transition", false, "Do not transition the ticket")
+	initLazyCmd.Flags().BoolVar(&glabIssueInitLArg, "issue", false, "Start from a GitLab issue instead of a merge request")
+	initLazyCmd.Flags().BoolVar(&createMrInitLArg, "create-mr", false, "Push the branch and create a draft merge request (resolving the issue with --issue)")
+	initLazyCmd.Flags().BoolVar(&refreshInitLArg, "refresh", false, "Fetch the ticket from the tracker, bypassing the local cache")
+	initLazyCmd.Flags().BoolVar(&codeOwnersInitLArg, "codeowners", false, "Request a review from the CODEOWNERS when creating the merge request")
 
 	initLazyCmd.MarkFlagRequired("branch-type")
 
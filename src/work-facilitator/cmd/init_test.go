@@ -0,0 +1,61 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package cmd
+
+import (
+	"testing"
+)
+
+func TestInitArgsCount(t *testing.T) {
+	tests := []struct {
+		name         string
+		createTicket bool
+		createMr     bool
+		args         []string
+		wantErr      bool
+	}{
+		{
+			name: "issue, title and branch type",
+			args: []string{"PROJ-12", "Fix login", "fix"},
+		},
+		{
+			name:    "issue missing",
+			args:    []string{"Fix login", "fix"},
+			wantErr: true,
+		},
+		{
+			name:         "created ticket, title and branch type",
+			createTicket: true,
+			args:         []string{"Fix login", "fix"},
+		},
+		{
+			name:         "created ticket with an issue",
+			createTicket: true,
+			args:         []string{"-", "Fix login", "fix"},
+			wantErr:      true,
+		},
+		{
+			name:     "created merge request, title and branch type",
+			createMr: true,
+			args:     []string{"Fix login", "fix"},
+		},
+		{
+			name:     "created merge request with an issue",
+			createMr: true,
+			args:     []string{"12", "Fix login", "fix"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createTicketInitArg, createMrInitArg = tt.createTicket, tt.createMr
+			t.Cleanup(func() { createTicketInitArg, createMrInitArg = false, false })
+
+			if err := initArgsCount(initCmd, tt.args); (err != nil) != tt.wantErr {
+				t.Errorf("initArgsCount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"os"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ownersCmd represents the owners command
+var ownersCmd = &cobra.Command{
+	Use:    "owners",
+	Short:  "Suggest reviewers from CODEOWNERS",
+	Long:   `List the CODEOWNERS owners of the files changed between the workflow branch and its reference branch`,
+	PreRun: ownersPreRunCommand,
+	Run:    ownersCommand,
+}
+
+func ownersPreRunCommand(cmd *cobra.Command, args []string) {
+	helper.WelcomeDisplay()
+	RootConfig = helper.NewConfig()
+	RootRepo = helper.NewRepo(RootConfig)
+
+	log.Debug("pre run owners")
+
+	if !RootRepo.HasCurrentWorkflow {
+		log.Warningln("No current workflow set up")
+		log.Warningln("Please use:")
+		log.Warningln("#> " + RootConfig.ScriptName + " use")
+		os.Exit(1)
+	}
+}
+
+func ownersCommand(cmd *cobra.Command, args []string) {
+	log.Debug("run owners")
+
+	wf := RootRepo.CurrentWorkflowData
+
+	helper.SpinStartDisplay("CODEOWNERS")
+	rules, err := helper.RepoCodeOwners(RootRepo.BasePath)
+	if err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+	if len(rules) == 0 {
+		helper.SpinStopDisplay("warning")
+		log.Warningln("No CODEOWNERS file found (root, .gitlab/, .github/ or docs/)")
+		return
+	}
+
+	helper.SpinUpdateDisplay("Changed files")
+	files, err := helper.RepoChangedFiles(wf.Branch, wf.RefBranch)
+	if err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+	helper.SpinStopDisplay("success")
+
+	if len(files) == 0 {
+		helper.SpinSideNoteDisplay("No changes on " + wf.Branch + " yet, default owners: " + strings.Join(helper.DefaultCodeOwners(rules), ", "))
+		helper.ByeByeDisplay()
+		return
+	}
+
+	rows := ownersRows(rules, files)
+	if len(rows) == 0 {
+		helper.SpinSideNoteDisplay("The " + strconv.Itoa(len(files)) + " changed file(s) have no owner")
+	} else {
+		helper.ShowTable([]string{"owner", "files", "reviewer"}, rows)
+	}
+
+	// Say GoodBye
+	helper.ByeByeDisplay()
+}
+
+// ownersRows returns, for each owner of the changed files, the number of files owned
+// and whether the owner can be requested as a reviewer
+func ownersRows(rules []helper.CodeOwnersRule, files []string) [][]string {
+	counts := map[string]int{}
+	for _, file := range files {
+		for _, owner := range helper.MatchCodeOwners(rules, []string{file}) {
+			counts[owner]++
+		}
+	}
+
+	var rows [][]string
+	for _, owner := range helper.MatchCodeOwners(rules, files) {
+		reviewer := "no"
+		if len(helper.CodeOwnersUsernames([]string{owner})) > 0 {
+			reviewer = "yes"
+		}
+		rows = append(rows, []string{owner, strconv.Itoa(counts[owner]), reviewer})
+	}
+	return rows
+}
+
+func init() {
+	rootCmd.AddCommand(ownersCmd)
+}
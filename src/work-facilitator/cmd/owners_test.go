@@ -0,0 +1,25 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package cmd
+
+import (
+	"reflect"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"testing"
+)
+
+func TestOwnersRows(t *testing.T) {
+	rules := helper.ParseCodeOwners("* @alice\n/docs/ @team/writers @bob\n")
+
+	expected := [][]string{
+		{"@alice", "2", "yes"},
+		{"@team/writers", "1", "no"},
+		{"@bob", "1", "yes"},
+	}
+
+	result := ownersRows(rules, []string{"main.go", "docs/guide.md", "cmd/root.go"})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("ownersRows() = %v, want %v", result, expected)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -14,8 +15,10 @@ import (
 
 var (
 	// Cmd Args
-	masterPauseArg string
-	forcePause     bool
+	masterPauseArg     string
+	forcePause         bool
+	noTransitionPause  bool
+	worklogDryRunPause bool
 
 	// local
 	checkoutToPause string
@@ -38,6 +41,15 @@ func pausePreRunCommand(cmd *cobra.Command, args []string) {
 	log.Debug("pre run pause")
 	helper.SpinStartDisplay("Verifications - pause...")
 
+	// A worktree stays on its workflow branch, the workflow would still be the current one there
+	if RootConfig.WorktreeEnabled {
+		helper.SpinStopDisplay("fail")
+		log.Warningln("pause is not available in worktree mode, the workflow stays checked out in its worktree")
+		log.Warningln("Leave the worktree to work on something else, or end the workflow:")
+		log.Warningln("#> " + RootConfig.ScriptName + " end")
+		os.Exit(1)
+	}
+
 	// Ensure we are in a workflow
 	if !RootRepo.HasCurrentWorkflow {
 		helper.SpinStopDisplay("warning")
@@ -115,15 +127,15 @@ func pauseCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	// Checkout
+	// Checkout and pull
 	helper.SpinUpdateDisplay("git checkout " + checkoutToPause)
 	helper.RepoCheckout(checkoutToPause, RootRepo.PublicAuthKey)
-	// Pull
 	helper.SpinUpdateDisplay("Git pull")
 	pullInfo := helper.RepoPull(RootRepo.PublicAuthKey)
 
 	// Delete current workflow
 	helper.SpinUpdateDisplay("Config update...")
+	pending, since := helper.RepoConfigStopWork(RootRepo.CurrentWorkflowName, time.Now())
 	helper.RepoConfigDeleteCurrentWorkflow()
 	helper.RepoConfigWrite()
 
@@ -131,6 +143,15 @@ func pauseCommand(cmd *cobra.Command, args []string) {
 	helper.SpinStopDisplay("success")
 	helper.SpinSideNoteDisplay("Pull info: " + pullInfo)
 
+	tracker := optionalTracker()
+	if !noTransitionPause {
+		transitionTicket(tracker, RootRepo.CurrentWorkflowData, c.EventPause)
+	}
+	if logWork(tracker, RootRepo.CurrentWorkflowData, pending, since, worklogDryRunPause) {
+		helper.RepoConfigResetWork(RootRepo.CurrentWorkflowName)
+		helper.RepoConfigWrite()
+	}
+
 	// Say GoodBye
 	helper.ByeByeDisplay()
 }
@@ -140,5 +161,7 @@ func init() {
 
 	pauseCmd.Flags().StringVarP(&masterPauseArg, "master", "m", c.GOMASTER, "Go master branch rather than ref-branch")
 	pauseCmd.Flags().BoolVarP(&forcePause, "force", "f", false, "Force pause workflow, skip uncommitted files check")
+	pauseCmd.Flags().BoolVar(&noTransitionPause, "no-transition", false, "Do not transition the ticket")
+	pauseCmd.Flags().BoolVar(&worklogDryRunPause, "worklog-dry-run", false, "Preview the worklog without posting it")
 
 }
@@ -0,0 +1,123 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"spirit-dev/work-facilitator/work-facilitator/ticketing"
+
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pickArgs = []string{
+		"branch_type\tBranch type, inferred from the ticket when omitted",
+	}
+)
+
+// pickCmd represents the pick command
+var pickCmd = &cobra.Command{
+	Use:       "pick [branch_type" + RootConfig.BranchContentStr + "] [flags]",
+	Short:     "initialize workflow from a picked ticket",
+	Long:      "Pick one of your open tickets and start a new workflow lazy style from it",
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: pickArgs,
+	PreRun:    pickPreRunCommand,
+	Run:       initLazyCommand,
+}
+
+func pickPreRunCommand(cmd *cobra.Command, args []string) {
+	helper.WelcomeDisplay()
+	RootConfig = helper.NewConfig()
+	RootRepo = helper.NewRepo(RootConfig)
+
+	log.Debug("pre run pick")
+	helper.SpinStartDisplay("Verifications - pick...")
+
+	// Checked before listing the tickets, not to pick one that cannot be started
+	if RootRepo.HasCurrentWorkflow {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln("You are in a workflow at this moment. Weird behaviour might occur")
+	}
+
+	RootTracker = newRootTracker()
+	lister, ok := RootTracker.(ticketing.Lister)
+	if !ok {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln("pick is not supported by the " + RootTracker.Name() + " ticketing")
+	}
+
+	helper.SpinUpdateDisplay("Fetching your open tickets")
+	tickets, err := lister.ListAssigned()
+	if err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+	if len(tickets) == 0 {
+		helper.SpinStopDisplay("warning")
+		log.Warningln("No open ticket assigned to you")
+		log.Warningln("You may run :")
+		log.Warningln("#> " + RootConfig.ScriptName + " initLazy issue branch_type")
+		os.Exit(0)
+	}
+	helper.SpinStopDisplay("success")
+
+	var options []string
+	for _, ticket := range tickets {
+		options = append(options, pickOption(ticket))
+	}
+	selected, err := pterm.DefaultInteractiveSelect.WithOptions(options).WithMaxHeight(15).Show("Pick a ticket")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	ticket := tickets[indexOf(options, selected)]
+	log.Debugf("picked: %+v\n", ticket)
+
+	// GitLab issues are started like initLazy --issue
+	glabIssueInitLArg = ticket.ID == 0 && ticket.IssueID != 0
+	branchType := ""
+	if len(args) > 0 {
+		branchType = args[0]
+	}
+	initLazyPrepare(ticket.Key, branchType)
+}
+
+// pickOption renders a ticket as a select option: reference, summary and status
+func pickOption(ticket ticketing.Ticket) string {
+	ref := ticket.Key
+	if ticket.IssueID != 0 {
+		ref = "#" + ticket.Key
+	} else if ticket.ID != 0 {
+		ref = "!" + ticket.Key
+	}
+
+	return fmt.Sprintf("%-12s %s [%s]", ref, ticket.Title, ticket.Status)
+}
+
+// indexOf returns the position of value in values, -1 when missing
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func init() {
+	rootCmd.AddCommand(pickCmd)
+
+	pickCmd.Flags().StringVarP(&commitTypeInitLArg, "commit-type", "c", c.NOTGIVEN, "Specify the commit type to be treated "+RootConfig.CommitTypeStr)
+	pickCmd.Flags().StringVarP(&refBranchInitLArg, "ref-branch", "r", c.NOTGIVENBRANCH, "Specify the source branch")
+	pickCmd.Flags().StringVarP(&titleSeparatorInitLArg, "separator", "s", c.NOTGIVEN, "Specify the separator in the branch title")
+	pickCmd.Flags().BoolVar(&noTransitionInitLArg, "no-transition", false, "Do not transition the ticket")
+	pickCmd.Flags().BoolVar(&refreshInitLArg, "refresh", false, "Fetch the ticket from the tracker, bypassing the local cache")
+
+	pickCmd.Flags().SortFlags = false
+}
@@ -0,0 +1,42 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package cmd
+
+import (
+	"spirit-dev/work-facilitator/work-facilitator/ticketing"
+	"testing"
+)
+
+func TestPickOption(t *testing.T) {
+	tests := []struct {
+		name     string
+		ticket   ticketing.Ticket
+		expected string
+	}{
+		{
+			name:     "jira issue",
+			ticket:   ticketing.Ticket{Key: "PROJ-12", Title: "Fix login", Status: "To Do"},
+			expected: "PROJ-12      Fix login [To Do]",
+		},
+		{
+			name:     "gitlab merge request",
+			ticket:   ticketing.Ticket{Key: "18", ID: 18, Title: "Add dark mode", Status: "opened"},
+			expected: "!18          Add dark mode [opened]",
+		},
+		{
+			name:     "gitlab issue",
+			ticket:   ticketing.Ticket{Key: "5", IssueID: 5, Title: "Broken pagination", Status: "opened"},
+			expected: "#5           Broken pagination [opened]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := pickOption(tt.ticket)
+			if result != tt.expected {
+				t.Errorf("pickOption() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"spirit-dev/work-facilitator/work-facilitator/ticketing"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// cmd Args
+	dryRunPrune bool
+)
+
+// pruneCandidate is a workflow whose work is over
+type pruneCandidate struct {
+	workflow string
+	branch   string
+	reason   string
+}
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:    "prune",
+	Short:  "Clean up finished workflows",
+	Long:   `Remove the workflows whose branch is gone on the remote, merged into the default branch, or whose merge request is merged or closed`,
+	PreRun: prunePreRunCommand,
+	Run:    pruneCommand,
+}
+
+func prunePreRunCommand(cmd *cobra.Command, args []string) {
+	helper.WelcomeDisplay()
+	RootConfig = helper.NewConfig()
+	RootRepo = helper.NewRepo(RootConfig)
+
+	log.Debug("pre run prune")
+}
+
+func pruneCommand(cmd *cobra.Command, args []string) {
+	log.Debug("run prune")
+
+	helper.SpinStartDisplay("Verifications - prune...")
+
+	// Tracking refs are read before the fetch, to tell a pushed branch from a local one
+	tracked := map[string]bool{}
+	for _, w := range RootRepo.Worklist {
+		tracked[w] = helper.RepoHasRemoteTrackingBranch(w)
+	}
+
+	helper.SpinUpdateDisplay("git fetch")
+	if err := helper.RepoFetchOrigin(RootRepo.PublicAuthKey); err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+	remoteBranches, err := helper.RepoRemoteBranches(RootRepo.PublicAuthKey)
+	if err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+
+	checker, _ := optionalTracker().(ticketing.MergeChecker)
+
+	var candidates []pruneCandidate
+	for _, w := range RootRepo.Worklist {
+		// The current workflow is checked out, it is never pruned
+		if RootRepo.HasCurrentWorkflow && w == RootRepo.CurrentWorkflowName {
+			continue
+		}
+		helper.SpinUpdateDisplay("Verifications - " + w)
+
+		wf := helper.RepoConfigGetCurrentWorkflow(w)
+		branch := wf.Branch
+		if branch == "" {
+			branch = w
+		}
+
+		// Only pushed branches are checked, a fresh local branch is always reachable from the default branch
+		gone := tracked[w] && !remoteBranches[branch]
+		merged := false
+		if tracked[w] && helper.RepoHasBranch(branch) {
+			merged, err = helper.RepoBranchMerged(branch, RootRepo.DefaultBranch)
+			if err != nil {
+				log.Debugln(err)
+			}
+		}
+		var mr ticketing.MergeState
+		if checker != nil && wf.Issue != 0 {
+			mr, err = checker.MergeState(wf.Issue)
+			if err != nil {
+				log.Debugln(err)
+			}
+		}
+
+		if reason := pruneReason(gone, merged, RootRepo.DefaultBranch, mr); reason != "" {
+			candidates = append(candidates, pruneCandidate{workflow: w, branch: branch, reason: reason})
+		}
+	}
+
+	helper.SpinUpdateDisplay("Verifications")
+	helper.SpinStopDisplay("success")
+
+	if len(candidates) == 0 {
+		helper.SpinSideNoteDisplay("Nothing to prune")
+		helper.ByeByeDisplay()
+		return
+	}
+
+	rows := [][]string{}
+	for _, candidate := range candidates {
+		rows = append(rows, []string{candidate.workflow, candidate.branch, candidate.reason})
+	}
+	helper.ShowTable([]string{"workflow", "branch", "reason"}, rows)
+
+	if dryRunPrune {
+		helper.SpinSideNoteDisplay("Dry run, nothing pruned")
+		helper.ByeByeDisplay()
+		return
+	}
+	if !helper.PromptUserConfirmation("Prune " + strconv.Itoa(len(candidates)) + " workflow(s)?") {
+		log.Fatalln("Operation cancelled by user")
+	}
+
+	helper.SpinStartDisplay("Git operations")
+	mainPath, worktrees, err := helper.RepoWorktrees()
+	if err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+	var pruned, removedWorktrees []string
+	for _, candidate := range candidates {
+		// A branch checked out in a worktree goes with it, the main worktree and worktrees with changes are kept
+		if path := worktrees[candidate.branch]; path != "" {
+			errW := fmt.Errorf("%s is checked out in the main worktree", candidate.branch)
+			if path != mainPath {
+				helper.SpinUpdateDisplay("git worktree remove " + path)
+				errW = helper.RepoWorktreeRemove(path, false)
+			}
+			if errW != nil {
+				helper.SpinStopDisplay("warning")
+				log.Warningln("Workflow " + candidate.workflow + " kept: " + errW.Error())
+				helper.SpinStartDisplay("Git operations")
+				continue
+			}
+			removedWorktrees = append(removedWorktrees, path)
+		}
+		if helper.RepoHasBranch(candidate.branch) {
+			helper.SpinUpdateDisplay("git branch -D " + candidate.branch)
+			helper.RepoDeleteBranch(candidate.branch, helper.RepoGetBranchRef(candidate.branch))
+		}
+		helper.RepoConfigDeleteWorkflow(candidate.workflow)
+		helper.RepoConfigDeleteBranch(candidate.workflow)
+		// In worktree mode, the workflow set as current is the last one used, possibly another worktree's
+		if helper.RepoConfigGetCurrentWorkflowName() == candidate.workflow {
+			helper.RepoConfigDeleteCurrentWorkflow()
+		}
+		pruned = append(pruned, candidate.workflow)
+	}
+	helper.RepoConfigWrite()
+	helper.SpinUpdateDisplay("Git operations")
+	helper.SpinStopDisplay("success")
+
+	for _, path := range removedWorktrees {
+		helper.SpinSideNoteDisplay("Worktree removed > " + path)
+	}
+	for _, workflow := range pruned {
+		helper.SpinSideNoteDisplay("Workflow pruned > " + workflow)
+	}
+
+	// Say GoodBye
+	helper.ByeByeDisplay()
+}
+
+// pruneReason explains why a workflow can be pruned, empty when it cannot.
+// A workflow whose merge request is still open is kept.
+func pruneReason(gone, merged bool, defaultBranch string, mr ticketing.MergeState) string {
+	if mr.State == "opened" {
+		return ""
+	}
+
+	var reasons []string
+	if gone {
+		reasons = append(reasons, "gone on remote")
+	}
+	if merged {
+		reasons = append(reasons, "merged into "+defaultBranch)
+	}
+	if mr.Merged {
+		reasons = append(reasons, "mr merged")
+	} else if mr.State == "closed" {
+		reasons = append(reasons, "mr closed")
+	}
+
+	return strings.Join(reasons, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().BoolVar(&dryRunPrune, "dry-run", false, "List the workflows to prune without removing them")
+}
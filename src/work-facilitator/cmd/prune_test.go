@@ -0,0 +1,60 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package cmd
+
+import (
+	"spirit-dev/work-facilitator/work-facilitator/ticketing"
+	"testing"
+)
+
+func TestPruneReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		gone     bool
+		merged   bool
+		mr       ticketing.MergeState
+		expected string
+	}{
+		{
+			name:     "active workflow",
+			expected: "",
+		},
+		{
+			name:     "branch gone on remote",
+			gone:     true,
+			expected: "gone on remote",
+		},
+		{
+			name:     "merged and gone",
+			gone:     true,
+			merged:   true,
+			expected: "gone on remote, merged into main",
+		},
+		{
+			name:     "merge request merged",
+			mr:       ticketing.MergeState{State: "merged", Merged: true},
+			expected: "mr merged",
+		},
+		{
+			name:     "merge request closed",
+			mr:       ticketing.MergeState{State: "closed"},
+			expected: "mr closed",
+		},
+		{
+			name:     "merge request still open",
+			merged:   true,
+			mr:       ticketing.MergeState{State: "opened"},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := pruneReason(tt.gone, tt.merged, "main", tt.mr)
+			if result != tt.expected {
+				t.Errorf("pruneReason() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
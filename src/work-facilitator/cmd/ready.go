@@ -0,0 +1,146 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"os"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"spirit-dev/work-facilitator/work-facilitator/ticketing"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// cmd Args
+	reviewersReady []string
+	labelsReady    []string
+	commentReady   string
+
+	// local
+	readyMarker ticketing.ReadyMarker
+)
+
+// readyCmd represents the ready command
+var readyCmd = &cobra.Command{
+	Use:    "ready",
+	Short:  "Mark the merge request ready for review",
+	Long:   `Remove the draft flag of the workflow merge request, request reviewers, add labels and optionally comment`,
+	PreRun: readyPreRunCommand,
+	Run:    readyCommand,
+}
+
+func readyPreRunCommand(cmd *cobra.Command, args []string) {
+	helper.WelcomeDisplay()
+	RootConfig = helper.NewConfig()
+	RootRepo = helper.NewRepo(RootConfig)
+
+	log.Debug("pre run ready")
+	helper.SpinStartDisplay("Verifications - ready...")
+
+	if !RootRepo.HasCurrentWorkflow {
+		helper.SpinStopDisplay("warning")
+		log.Warningln("No current workflow set up")
+		log.Warningln("Please use:")
+		log.Warningln("#> " + RootConfig.ScriptName + " use")
+		os.Exit(1)
+	}
+
+	RootTracker = newRootTracker()
+	marker, ok := RootTracker.(ticketing.ReadyMarker)
+	if !ok {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(RootTracker.Name() + " does not handle merge requests")
+	}
+	readyMarker = marker
+
+	if RootRepo.CurrentWorkflowData.Issue == 0 {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln("No merge request linked to the workflow, create one with `init --create-mr`")
+	}
+
+	helper.SpinUpdateDisplay("Verifications")
+	helper.SpinStopDisplay("success")
+}
+
+func readyCommand(cmd *cobra.Command, args []string) {
+	log.Debug("run ready")
+
+	id := RootRepo.CurrentWorkflowData.Issue
+	mr := "!" + strconv.Itoa(id)
+
+	helper.SpinStartDisplay("Merge request " + mr + " ready")
+	if err := readyMarker.MarkReady(id); err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+	helper.SpinStopDisplay("success")
+
+	// Reviewers, labels and comment are best effort, the merge request being ready already
+	reviewers, source := readyReviewers()
+	if len(reviewers) == 0 {
+		helper.SpinSideNoteDisplay("No reviewers to request, set ticketing.gitlab.reviewers or a CODEOWNERS file")
+	} else {
+		helper.SpinStartDisplay("Reviewers")
+		unknown, err := readyMarker.AssignReviewers(id, reviewers)
+		if err != nil {
+			helper.SpinStopDisplay("warning")
+			log.Warningln("Reviewers not requested: " + err.Error())
+		} else {
+			helper.SpinStopDisplay("success")
+			helper.SpinSideNoteDisplay("Review requested from " + strings.Join(reviewers, ", ") + " (" + source + ")")
+			if len(unknown) > 0 {
+				log.Warningln("Unknown reviewers skipped: " + strings.Join(unknown, ", "))
+			}
+		}
+	}
+
+	labels := append(append([]string{}, RootConfig.TicketingGlabReadyLabels...), labelsReady...)
+	if len(labels) > 0 {
+		helper.SpinStartDisplay("Labels")
+		if err := readyMarker.AddLabels(id, labels); err != nil {
+			helper.SpinStopDisplay("warning")
+			log.Warningln("Labels not added: " + err.Error())
+		} else {
+			helper.SpinStopDisplay("success")
+			helper.SpinSideNoteDisplay("Labels added: " + strings.Join(labels, ", "))
+		}
+	}
+
+	if commentReady != "" {
+		helper.SpinStartDisplay("Comment")
+		if err := readyMarker.CommentMergeRequest(id, commentReady); err != nil {
+			helper.SpinStopDisplay("warning")
+			log.Warningln("Comment not posted: " + err.Error())
+		} else {
+			helper.SpinStopDisplay("success")
+		}
+	}
+
+	// Say GoodBye
+	helper.ByeByeDisplay()
+}
+
+// readyReviewers returns the reviewers to request and where they come from:
+// the --reviewer flags, the config, or the CODEOWNERS owners of the workflow changes
+func readyReviewers() ([]string, string) {
+	if len(reviewersReady) > 0 {
+		return reviewersReady, "flags"
+	}
+	if len(RootConfig.TicketingGlabReviewers) > 0 {
+		return RootConfig.TicketingGlabReviewers, "config"
+	}
+
+	return helper.CodeOwnersUsernames(codeOwners(RootRepo.CurrentWorkflowData)), "CODEOWNERS"
+}
+
+func init() {
+	rootCmd.AddCommand(readyCmd)
+
+	readyCmd.Flags().StringSliceVarP(&reviewersReady, "reviewer", "r", nil, "Reviewer username, overrides the configured reviewers (repeatable)")
+	readyCmd.Flags().StringSliceVarP(&labelsReady, "label", "l", nil, "Label to add on top of the configured ones (repeatable)")
+	readyCmd.Flags().StringVarP(&commentReady, "comment", "m", "", "Comment to post on the merge request")
+}
@@ -6,14 +6,16 @@ package cmd
 import (
 	"os"
 	"spirit-dev/work-facilitator/work-facilitator/common"
+	"spirit-dev/work-facilitator/work-facilitator/ticketing"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	RootConfig common.Config
-	RootRepo   common.Repo
+	RootConfig  common.Config
+	RootRepo    common.Repo
+	RootTracker ticketing.Tracker
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -44,4 +46,12 @@ func init() {
 	if err := viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile")); err != nil {
 		panic(err)
 	}
+	rootCmd.PersistentFlags().String("record", "", "development: record the ticketing API exchanges, secrets scrubbed, into this cassette file")
+	if err := viper.BindPFlag("record", rootCmd.PersistentFlags().Lookup("record")); err != nil {
+		panic(err)
+	}
+	rootCmd.PersistentFlags().String("cd-file", "", "worktree mode: write the directory to change into in this file, used by the completion shell functions")
+	if err := viper.BindPFlag("cd_file", rootCmd.PersistentFlags().Lookup("cd-file")); err != nil {
+		panic(err)
+	}
 }
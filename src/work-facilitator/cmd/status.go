@@ -5,12 +5,26 @@ package cmd
 
 import (
 	"os"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"spirit-dev/work-facilitator/work-facilitator/ticketing"
+	"strconv"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+var (
+	watchStatus   bool
+	refreshStatus bool
+
+	// local
+	ticketStatus ticketing.Ticket
+
+	pipelinePollInterval = 10 * time.Second
+)
+
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
 	Use:    "status",
@@ -36,6 +50,19 @@ func statusPreRunCommand(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	// The ticket comes from the local cache while fresh, or when the tracker is unreachable
+	wf := RootRepo.CurrentWorkflowData
+	if tracker := optionalTracker(); tracker != nil && workflowTicketKey(wf) != "" {
+		helper.SpinUpdateDisplay("Verifications - ticket " + workflowTicketKey(wf))
+		ticket, err := workflowTicket(tracker, wf, refreshStatus)
+		if err != nil {
+			helper.SpinStopDisplay("warning")
+			log.Warningln(err)
+			helper.SpinStartDisplay("Verifications...")
+		}
+		ticketStatus = ticket
+	}
+
 	helper.SpinUpdateDisplay("Verifications")
 	helper.SpinStopDisplay("success")
 }
@@ -49,13 +76,90 @@ func statusCommand(cmd *cobra.Command, args []string) {
 	helper.SpinUpdateDisplay("Git operations")
 	helper.SpinStopDisplay("success")
 
-	helper.ShowSummary(RootConfig, RootRepo.CurrentWorkflowData)
+	helper.ShowSummary(RootRepo.CurrentWorkflowData, workflowRefs(RootRepo.CurrentWorkflowData))
+	if ticketStatus.Title != "" {
+		helper.SpinSideNoteDisplay("Ticket " + workflowTicketKey(RootRepo.CurrentWorkflowData) + ": " + ticketStatus.Title + " [" + ticketStatus.Status + "]")
+	}
 	helper.ShowBox(status)
 
+	if review, ok := mergeRequestReview(RootRepo.CurrentWorkflowData); ok {
+		helper.ShowReview(review)
+	}
+
+	pipeline, ok, err := latestPipeline(RootRepo.CurrentWorkflowData)
+	if ok && pipeline.ID != 0 {
+		helper.ShowPipeline(pipeline)
+	}
+
 	// Say GoodBye
 	helper.ByeByeDisplay()
+
+	// Scripts waiting on --watch must not mistake a pipeline that could not be fetched for a success
+	if watchStatus && (err != nil || pipeline.Status == "failed") {
+		os.Exit(1)
+	}
+}
+
+// mergeRequestReview fetches the review state of the workflow merge request (mrref).
+// Nothing is reported when the tracker has no merge requests or the workflow none yet.
+func mergeRequestReview(wf c.Workflow) (c.Review, bool) {
+	reporter, ok := optionalTracker().(ticketing.ReviewReporter)
+	if !ok || wf.Issue == 0 {
+		return c.Review{}, false
+	}
+
+	helper.SpinStartDisplay("Merge request review")
+	review, err := reporter.Review(wf.Issue)
+	if err != nil {
+		helper.SpinStopDisplay("warning")
+		log.Warningln(err)
+		return review, false
+	}
+
+	helper.SpinUpdateDisplay("Merge request review")
+	helper.SpinStopDisplay("success")
+	return review, true
+}
+
+// latestPipeline fetches the pipeline of the workflow branch, polling until it finishes with --watch.
+// Nothing is reported when the tracker has no pipelines, the fetch error being returned otherwise.
+func latestPipeline(wf c.Workflow) (c.Pipeline, bool, error) {
+	reporter, ok := optionalTracker().(ticketing.PipelineReporter)
+	if !ok {
+		return c.Pipeline{}, false, nil
+	}
+
+	helper.SpinStartDisplay("Pipeline")
+	pipeline, err := reporter.LatestPipeline(wf.Branch)
+	for err == nil && watchStatus && pipeline.ID != 0 && !pipeline.Finished() {
+		helper.SpinUpdateDisplay("Pipeline #" + strconv.Itoa(pipeline.ID) + " " + pipeline.Status + ", waiting...")
+		time.Sleep(pipelinePollInterval)
+		pipeline, err = reporter.LatestPipeline(wf.Branch)
+	}
+	if err != nil {
+		helper.SpinStopDisplay("warning")
+		log.Warningln(err)
+		return pipeline, false, err
+	}
+
+	helper.SpinUpdateDisplay("Pipeline")
+	if pipeline.ID == 0 {
+		helper.SpinStopDisplay("warning")
+		log.Warningln("No pipeline found for branch " + wf.Branch)
+		return pipeline, true, nil
+	}
+	if pipeline.Status == "failed" {
+		helper.SpinStopDisplay("fail")
+	} else {
+		helper.SpinStopDisplay("success")
+	}
+
+	return pipeline, true, nil
 }
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().BoolVar(&refreshStatus, "refresh", false, "Fetch the ticket from the tracker, bypassing the local cache")
+	statusCmd.Flags().BoolVarP(&watchStatus, "watch", "w", false, "Poll the branch pipeline until it finishes, exit non-zero if it failed or could not be fetched")
 }
@@ -0,0 +1,125 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package cmd
+
+import (
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"spirit-dev/work-facilitator/work-facilitator/ticketing"
+	"strings"
+	"testing"
+)
+
+// fakeIssueTracker records the tickets and issues fetched, as a GitLab tracker would serve them
+type fakeIssueTracker struct {
+	fetched []string
+}
+
+func (f *fakeIssueTracker) Name() string { return c.GITLAB }
+
+func (f *fakeIssueTracker) ParseKey(key string) (ticketing.Ticket, error) {
+	return ticketing.Ticket{Key: strings.TrimPrefix(key, "#")}, nil
+}
+
+func (f *fakeIssueTracker) FetchTicket(key string) (ticketing.Ticket, error) {
+	f.fetched = append(f.fetched, "ticket "+key)
+	return ticketing.Ticket{Key: key, Title: "merge request " + key}, nil
+}
+
+func (f *fakeIssueTracker) FetchIssue(key string) (ticketing.Ticket, error) {
+	f.fetched = append(f.fetched, "issue "+key)
+	return ticketing.Ticket{Key: key, Title: "issue " + key}, nil
+}
+
+func (f *fakeIssueTracker) WorkflowContext(cfg c.Config, ticket ticketing.Ticket, branchType, commitType string) (string, string) {
+	return "", ""
+}
+
+func (f *fakeIssueTracker) Refs(wf c.Workflow) []c.TicketRef { return nil }
+
+func TestWorkflowTicket(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	config, repo := RootConfig, RootRepo
+	t.Cleanup(func() { RootConfig, RootRepo = config, repo })
+	RootConfig = c.Config{Ticketing: c.GITLAB, ScriptName: "work-facilitator", TicketingCacheTTL: 60}
+	RootRepo = c.Repo{FName: "group/project"}
+
+	// Reloaded from the git config, GitLab workflows have no ticket param
+	tests := []struct {
+		name          string
+		wf            c.Workflow
+		expectedKey   string
+		expectedTitle string
+		expected      string
+	}{
+		{
+			name:          "merge request",
+			wf:            c.Workflow{Issue: 18},
+			expectedKey:   "18",
+			expectedTitle: "merge request 18",
+			expected:      "ticket 18",
+		},
+		{
+			name:          "linked issue",
+			wf:            c.Workflow{Issue: 19, LinkedIssue: 5},
+			expectedKey:   "5",
+			expectedTitle: "issue 5",
+			expected:      "issue 5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if key := workflowTicketKey(tt.wf); key != tt.expectedKey {
+				t.Errorf("workflowTicketKey() = %v, want %v", key, tt.expectedKey)
+			}
+
+			tracker := &fakeIssueTracker{}
+			for i := 0; i < 2; i++ {
+				ticket, err := workflowTicket(tracker, tt.wf, false)
+				if err != nil {
+					t.Fatalf("workflowTicket() unexpected error: %v", err)
+				}
+				if ticket.Title != tt.expectedTitle {
+					t.Errorf("Title = %v, want %v", ticket.Title, tt.expectedTitle)
+				}
+			}
+			// The second lookup is served by the cache
+			if len(tracker.fetched) != 1 || tracker.fetched[0] != tt.expected {
+				t.Errorf("fetched = %v, want [%v]", tracker.fetched, tt.expected)
+			}
+		})
+	}
+
+	if key := workflowTicketKey(c.Workflow{Ticket: "PROJ-12", Issue: 18}); key != "PROJ-12" {
+		t.Errorf("workflowTicketKey() = %v, want the saved ticket PROJ-12", key)
+	}
+}
+
+func TestFetchCached(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	config, repo := RootConfig, RootRepo
+	t.Cleanup(func() { RootConfig, RootRepo = config, repo })
+	RootConfig = c.Config{Ticketing: c.GITLAB, ScriptName: "work-facilitator", TicketingCacheTTL: 60}
+	RootRepo = c.Repo{FName: "group/project"}
+
+	// `#18` and `18` share the cache entry of the normalised key
+	tracker := &fakeIssueTracker{}
+	for _, key := range []string{"#18", "18"} {
+		if _, err := fetchCached(tracker, "ticket", key, false, func() (ticketing.Ticket, error) { return tracker.FetchTicket("18") }); err != nil {
+			t.Fatalf("fetchCached(%v) unexpected error: %v", key, err)
+		}
+	}
+	if len(tracker.fetched) != 1 {
+		t.Errorf("fetched = %v, want a single fetch", tracker.fetched)
+	}
+
+	// Without ttl, the cache is off and each lookup reaches the tracker
+	RootConfig.TicketingCacheTTL = 0
+	if _, err := fetchCached(tracker, "ticket", "18", false, func() (ticketing.Ticket, error) { return tracker.FetchTicket("18") }); err != nil {
+		t.Fatalf("fetchCached() unexpected error: %v", err)
+	}
+	if len(tracker.fetched) != 2 {
+		t.Errorf("fetched = %v, want the tracker reached without cache", tracker.fetched)
+	}
+}
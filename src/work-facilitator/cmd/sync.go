@@ -0,0 +1,181 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"os"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Cmd Args
+	strategySync string
+	continueSync bool
+	abortSync    bool
+	pushSync     bool
+
+	// local
+	inProgressSync string
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:    "sync",
+	Short:  "Update the workflow branch from its ref branch",
+	Long:   `Fetch origin, then rebase the workflow branch onto origin/<refbranch> (or merge it), stopping on conflicts`,
+	PreRun: syncPreRunCommand,
+	Run:    syncCommand,
+}
+
+func syncPreRunCommand(cmd *cobra.Command, args []string) {
+	helper.WelcomeDisplay()
+	RootConfig = helper.NewConfig()
+	RootRepo = helper.NewRepo(RootConfig)
+
+	log.Debug("pre run sync")
+	helper.SpinStartDisplay("Verifications - sync...")
+
+	if !RootRepo.HasCurrentWorkflow {
+		helper.SpinStopDisplay("warning")
+		log.Warningln("No current workflow set up")
+		log.Warningln("Please use:")
+		log.Warningln("#> " + RootConfig.ScriptName + " use")
+		os.Exit(1)
+	}
+
+	if strategySync == "" {
+		strategySync = RootConfig.SyncStrategy
+	}
+	if strategySync != c.SyncRebase && strategySync != c.SyncMerge {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln("Unknown sync strategy '" + strategySync + "' (rebase or merge)")
+	}
+
+	inProgressSync = helper.RepoSyncInProgress()
+	switch {
+	case (continueSync || abortSync) && inProgressSync == "":
+		helper.SpinStopDisplay("fail")
+		log.Fatalln("No sync in progress")
+	case !continueSync && !abortSync && inProgressSync != "":
+		helper.SpinStopDisplay("warning")
+		log.Warningln("A " + inProgressSync + " is in progress, resolve the conflicts then use:")
+		log.Warningln("#> " + RootConfig.ScriptName + " sync --continue")
+		log.Warningln("or give up with:")
+		log.Warningln("#> " + RootConfig.ScriptName + " sync --abort")
+		os.Exit(1)
+	case inProgressSync == "":
+		// A fresh sync starts from the workflow branch, clean
+		wf := RootRepo.CurrentWorkflowData
+		if head := helper.RepoHead(); head.Name().Short() != wf.Branch {
+			helper.SpinStopDisplay("fail")
+			log.Fatalln("The workflow branch " + wf.Branch + " is not checked out, use `" + RootConfig.ScriptName + " use`")
+		}
+		uncommittedFiles, hasUncommitted, err := helper.RepoCheckUncommittedFiles()
+		if err != nil {
+			helper.SpinStopDisplay("fail")
+			log.Fatalln("Error checking repository status:", err)
+		}
+		if hasUncommitted {
+			helper.SpinStopDisplay("fail")
+			helper.DisplayUncommittedFiles(uncommittedFiles)
+			log.Fatalln("Uncommitted files detected. Please commit or stash changes before syncing.")
+		}
+	}
+
+	helper.SpinUpdateDisplay("Verifications")
+	helper.SpinStopDisplay("success")
+}
+
+func syncCommand(cmd *cobra.Command, args []string) {
+	log.Debug("run sync")
+
+	wf := RootRepo.CurrentWorkflowData
+	upstream := "origin/" + wf.RefBranch
+
+	if abortSync {
+		helper.SpinStartDisplay("git " + inProgressSync + " --abort")
+		if err := helper.RepoSyncAbort(inProgressSync); err != nil {
+			helper.SpinStopDisplay("fail")
+			log.Fatalln(err)
+		}
+		helper.SpinStopDisplay("success")
+		helper.SpinSideNoteDisplay("Sync aborted, " + wf.Branch + " is back as it was")
+		helper.ByeByeDisplay()
+		return
+	}
+
+	var conflicts []string
+	var err error
+	if continueSync {
+		helper.SpinStartDisplay("git " + inProgressSync + " --continue")
+		conflicts, err = helper.RepoSyncContinue(inProgressSync)
+	} else {
+		helper.SpinStartDisplay("Git operations")
+		helper.SpinUpdateDisplay("git fetch")
+		if err := helper.RepoFetchOrigin(RootRepo.PublicAuthKey); err != nil {
+			helper.SpinStopDisplay("fail")
+			log.Fatalln(err)
+		}
+		helper.SpinUpdateDisplay("git " + strategySync + " " + upstream)
+		conflicts, err = helper.RepoSync(strategySync, upstream)
+	}
+	if err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+	if len(conflicts) > 0 {
+		helper.SpinStopDisplay("fail")
+		log.Warningln("Conflicts to resolve:")
+		for _, file := range conflicts {
+			log.Warningln("\t - " + file)
+		}
+		log.Warningln("Resolve them and stage them with `git add`, then use:")
+		log.Warningln("#> " + RootConfig.ScriptName + " sync --continue")
+		log.Warningln("or give up with:")
+		log.Warningln("#> " + RootConfig.ScriptName + " sync --abort")
+		os.Exit(1)
+	}
+	helper.SpinStopDisplay("success")
+	helper.SpinSideNoteDisplay(wf.Branch + " is up to date with " + upstream)
+
+	if pushSync {
+		syncPush(wf.Branch)
+	}
+
+	// Say GoodBye
+	helper.ByeByeDisplay()
+}
+
+// syncPush pushes the synced branch, forcing with lease once it has been pushed,
+// the rebase rewriting its commits
+func syncPush(branch string) {
+	if !helper.RepoHasRemoteTrackingBranch(branch) {
+		helper.SpinStartDisplay("git push")
+		helper.RepoPush(RootRepo.PublicAuthKey, branch)
+		helper.SpinStopDisplay("success")
+		return
+	}
+
+	helper.SpinStartDisplay("git push --force-with-lease")
+	if err := helper.RepoPushForceWithLease(RootRepo.PublicAuthKey, branch); err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln("Push failed, origin/" + branch + " may have changed since the last fetch: " + err.Error())
+	}
+	helper.SpinStopDisplay("success")
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().StringVarP(&strategySync, "strategy", "s", "", "rebase or merge, defaults to global.sync_strategy (rebase)")
+	syncCmd.Flags().BoolVar(&continueSync, "continue", false, "Continue the sync once the conflicts are resolved and staged")
+	syncCmd.Flags().BoolVar(&abortSync, "abort", false, "Abort the sync in progress")
+	syncCmd.Flags().BoolVarP(&pushSync, "push", "p", false, "Push the synced branch (with --force-with-lease once pushed)")
+
+	syncCmd.MarkFlagsMutuallyExclusive("continue", "abort")
+}
@@ -0,0 +1,335 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"spirit-dev/work-facilitator/work-facilitator/ticketing"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	// trackerRecorder records the ticketing API exchanges with --record
+	trackerRecorder *ticketing.Recorder
+)
+
+// newRootTracker builds the tracker of the configured ticketing system, and validates its credentials
+func newRootTracker() ticketing.Tracker {
+	tracker, err := ticketing.New(RootConfig, RootRepo, trackerClient())
+	if err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+	if validator, ok := tracker.(ticketing.Validator); ok {
+		err := validator.Validate()
+		if errors.Is(err, ticketing.ErrUnreachable) && RootConfig.TicketingCacheTTL > 0 {
+			// Offline, cached tickets may still be served
+			helper.SpinStopDisplay("warning")
+			log.Warningln(err)
+			helper.SpinStartDisplay("Verifications...")
+		} else if err != nil {
+			helper.SpinStopDisplay("fail")
+			log.Fatalln(err)
+		}
+	}
+	return tracker
+}
+
+// trackerClient returns the http client recording the ticketing API exchanges with --record, nil otherwise.
+// A single recorder is shared by the trackers built during the command.
+func trackerClient() *http.Client {
+	path := viper.GetString("record")
+	if path == "" {
+		return nil
+	}
+	if trackerRecorder == nil {
+		log.Debugln("Recording ticketing exchanges into " + path)
+		trackerRecorder = ticketing.NewRecorder(path, nil, []string{
+			RootConfig.TicketingJiraPassword,
+			RootConfig.TicketingJiraToken,
+			RootConfig.TicketingGlabToken,
+			RootConfig.TicketingGithubToken,
+			RootConfig.TicketingGiteaToken,
+			RootConfig.TicketingBitbucketToken,
+			RootConfig.TicketingAzureToken,
+		})
+	}
+	return &http.Client{Transport: trackerRecorder, Timeout: 30 * time.Second}
+}
+
+// ticketCache opens the local ticket cache, nil when disabled or unavailable
+func ticketCache() *ticketing.Cache {
+	if RootConfig.TicketingCacheTTL <= 0 {
+		return nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		log.Debugln("ticket cache disabled: " + err.Error())
+		return nil
+	}
+	return ticketing.OpenCache(filepath.Join(dir, RootConfig.ScriptName, "tickets.json"), time.Duration(RootConfig.TicketingCacheTTL)*time.Minute)
+}
+
+// fetchCached fetches a ticket through the local cache, refresh bypassing it.
+// A cached ticket is used, with a warning, when the tracker cannot be reached.
+// Entries are keyed on the key normalised by the tracker, `AB#1`, `#1` and `1` sharing the same one.
+func fetchCached(tracker ticketing.Tracker, kind, key string, refresh bool, fetch func() (ticketing.Ticket, error)) (ticketing.Ticket, error) {
+	if parsed, err := tracker.ParseKey(key); err == nil && parsed.Key != "" {
+		key = parsed.Key
+	}
+	cacheKey := strings.Join([]string{RootConfig.Ticketing, RootRepo.FName, kind, key}, "/")
+	ticket, err := ticketCache().Fetch(cacheKey, refresh, fetch)
+	if ticketing.IsStale(err) {
+		helper.SpinStopDisplay("warning")
+		log.Warningln(err)
+		helper.SpinStartDisplay("Verifications...")
+		return ticket, nil
+	}
+	return ticket, err
+}
+
+// workflowTicketKey returns the key of the ticket a workflow was started from.
+// Only Jira and Azure save it (ticket), the other trackers refer to the linked issue or the merge request.
+func workflowTicketKey(wf c.Workflow) string {
+	switch {
+	case wf.Ticket != "":
+		return wf.Ticket
+	case wf.LinkedIssue != 0:
+		return strconv.Itoa(wf.LinkedIssue)
+	case wf.Issue != 0:
+		return strconv.Itoa(wf.Issue)
+	}
+	return ""
+}
+
+// workflowTicket fetches the ticket a workflow was started from, through the local cache
+func workflowTicket(tracker ticketing.Tracker, wf c.Workflow, refresh bool) (ticketing.Ticket, error) {
+	key := workflowTicketKey(wf)
+	if fetcher, ok := tracker.(ticketing.IssueFetcher); ok && wf.LinkedIssue != 0 && key == strconv.Itoa(wf.LinkedIssue) {
+		return fetchCached(tracker, "issue", key, refresh, func() (ticketing.Ticket, error) { return fetcher.FetchIssue(key) })
+	}
+	return fetchCached(tracker, "ticket", key, refresh, func() (ticketing.Ticket, error) { return tracker.FetchTicket(key) })
+}
+
+// optionalTracker builds the tracker of the configured ticketing system, nil if none is usable
+func optionalTracker() ticketing.Tracker {
+	tracker, err := ticketing.New(RootConfig, RootRepo, trackerClient())
+	if err != nil {
+		log.Debugln(err)
+		return nil
+	}
+	return tracker
+}
+
+// workflowRefs returns the ticket references of a workflow, if a ticketing system is enabled
+func workflowRefs(wf c.Workflow) []c.TicketRef {
+	tracker := optionalTracker()
+	if tracker == nil {
+		return nil
+	}
+	return tracker.Refs(wf)
+}
+
+// transitionTicket moves the workflow ticket with the transition configured for the lifecycle event.
+// Failures are reported as warnings, the git operations being already done.
+func transitionTicket(tracker ticketing.Tracker, wf c.Workflow, event string) {
+	transitioner, ok := tracker.(ticketing.Transitioner)
+	if !ok || wf.Ticket == "" {
+		return
+	}
+	name := transitioner.TransitionName(wf.Ticket, event)
+	if name == "" {
+		log.Debugln("No transition configured for event " + event)
+		return
+	}
+
+	helper.SpinStartDisplay("Ticket transition " + wf.Ticket + " > " + name)
+	if err := transitioner.Transition(wf.Ticket, name); err != nil {
+		helper.SpinStopDisplay("warning")
+		log.Warningln("Ticket " + wf.Ticket + " not transitioned: " + err.Error())
+		return
+	}
+	helper.SpinStopDisplay("success")
+	helper.SpinSideNoteDisplay(wf.Ticket + " > " + name)
+}
+
+// logWork offers to post the time worked on a workflow as a worklog on its ticket.
+// It returns true once the worklog is posted.
+func logWork(tracker ticketing.Tracker, wf c.Workflow, pending time.Duration, since time.Time, dryRun bool) bool {
+	worklogger, ok := tracker.(ticketing.Worklogger)
+	if !RootConfig.TicketingJiraWorklogEnabled || !ok || wf.Ticket == "" {
+		return false
+	}
+
+	spent := helper.RoundDuration(pending, RootConfig.TicketingJiraWorklogRound, RootConfig.TicketingJiraWorklogRounding)
+	if spent < time.Minute {
+		log.Debugln("No time to log on " + wf.Ticket)
+		return false
+	}
+
+	preview := fmt.Sprintf("%s on %s, started %s (worked %s)", spent, wf.Ticket, since.Format("2006-01-02 15:04"), pending.Truncate(time.Second))
+	if dryRun {
+		helper.SpinSideNoteDisplay("Worklog dry run: " + preview)
+		return false
+	}
+	if !helper.PromptUserConfirmation("Post worklog " + preview + "?") {
+		helper.SpinSideNoteDisplay("Worklog not posted")
+		return false
+	}
+
+	helper.SpinStartDisplay("Ticket worklog " + wf.Ticket)
+	if err := worklogger.AddWorklog(wf.Ticket, since, spent); err != nil {
+		helper.SpinStopDisplay("warning")
+		log.Warningln("Worklog not posted: " + err.Error())
+		return false
+	}
+	helper.SpinStopDisplay("success")
+	helper.SpinSideNoteDisplay("Worklog posted: " + preview)
+
+	return true
+}
+
+// codeOwners returns the CODEOWNERS owners of the files changed on the workflow branch,
+// the default owners when nothing changed yet (e.g. right after init)
+func codeOwners(wf c.Workflow) []string {
+	rules, err := helper.RepoCodeOwners(RootRepo.BasePath)
+	if err != nil {
+		log.Warningln("CODEOWNERS not read: " + err.Error())
+		return nil
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	files, err := helper.RepoChangedFiles(wf.Branch, wf.RefBranch)
+	if err != nil {
+		log.Debugln("Changed files unknown: " + err.Error())
+	}
+	if len(files) == 0 {
+		return helper.DefaultCodeOwners(rules)
+	}
+	return helper.MatchCodeOwners(rules, files)
+}
+
+// codeOwnersReviewers returns the CODEOWNERS users to request a review from when creating a merge request, if enabled
+func codeOwnersReviewers(wf c.Workflow, enabled bool) []string {
+	if !enabled {
+		return nil
+	}
+	reviewers := helper.CodeOwnersUsernames(codeOwners(wf))
+	log.Debugln("CODEOWNERS reviewers: " + strings.Join(reviewers, ", "))
+	return reviewers
+}
+
+// createMergeRequest pushes the workflow branch and opens a draft merge request for it,
+// labelled with the branch type. The workflow is updated with the merge request reference.
+func createMergeRequest(workflow c.Workflow, mr ticketing.MergeRequest) c.Workflow {
+	helper.SpinUpdateDisplay("git push")
+	helper.RepoPush(RootRepo.PublicAuthKey, workflow.Branch)
+
+	helper.SpinUpdateDisplay("Merge request creation")
+	mr.SourceBranch = workflow.Branch
+	mr.TargetBranch = workflow.RefBranch
+	mr.Labels = []string{workflow.BranchType}
+	mr.Draft = true
+	created, err := RootTracker.(ticketing.MergeRequester).CreateMergeRequest(mr)
+	if err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+
+	return withMergeRequest(workflow, created)
+}
+
+// mergeRequestTitle returns the title of a merge request created for the workflow,
+// prefixed with the ticket key when tickets and merge requests live apart
+func mergeRequestTitle(workflow c.Workflow) string {
+	if _, apart := RootTracker.(ticketing.MergeRequestFinder); apart && workflow.Ticket != "" {
+		return workflow.Ticket + " " + workflow.Title
+	}
+	return workflow.Title
+}
+
+// linkMergeRequest looks up the open merge request of the workflow branch, for trackers
+// whose tickets and merge requests live apart. The workflow is updated with its reference.
+func linkMergeRequest(workflow c.Workflow) c.Workflow {
+	finder, ok := RootTracker.(ticketing.MergeRequestFinder)
+	if !ok {
+		return workflow
+	}
+
+	helper.SpinUpdateDisplay("Merge request lookup")
+	mr, err := finder.FindMergeRequest(workflow.Branch)
+	if err != nil {
+		log.Warningln("Merge request lookup failed: " + err.Error())
+		return workflow
+	}
+	if mr.ID == 0 {
+		log.Debugln("No merge request for " + workflow.Branch)
+		return workflow
+	}
+
+	return withMergeRequest(workflow, mr)
+}
+
+// withMergeRequest stores the merge request reference in the workflow and refreshes its commit prefix
+func withMergeRequest(workflow c.Workflow, mr ticketing.Ticket) c.Workflow {
+	workflow.Issue = mr.ID
+	ticket := ticketing.Ticket{
+		Key:     workflow.Ticket,
+		ID:      mr.ID,
+		IssueID: workflow.LinkedIssue,
+		Title:   workflow.Title,
+		Branch:  workflow.Branch,
+	}
+	_, workflow.Commit = RootTracker.WorkflowContext(RootConfig, ticket, workflow.BranchType, workflow.CommitType)
+	helper.RepoConfigUpdateWorkflowTicket(workflow, RootTracker.Refs(workflow))
+
+	return workflow
+}
+
+// unpushedCommits lists the commits about to be pushed, when they are to be commented on the ticket
+func unpushedCommits(wf c.Workflow) []c.CommitInfo {
+	if !RootConfig.TicketingJiraCommentOnPush || wf.Ticket == "" {
+		return nil
+	}
+
+	commits, err := helper.RepoUnpushedCommits(wf.Branch, wf.RefBranch)
+	if err != nil {
+		log.Debugln(err)
+	}
+	return commits
+}
+
+// commentCommits reports the pushed commits on the ticket of the workflow.
+// Failures are reported as warnings, the push being done already.
+func commentCommits(wf c.Workflow, commits []c.CommitInfo) {
+	if len(commits) == 0 {
+		return
+	}
+	commenter, ok := optionalTracker().(ticketing.Commenter)
+	if !ok {
+		return
+	}
+
+	helper.SpinStartDisplay("Ticket comment " + wf.Ticket)
+	if err := commenter.CommentCommits(wf.Ticket, wf.Branch, RootRepo.BrowserUrl, commits); err != nil {
+		helper.SpinStopDisplay("warning")
+		log.Warningln("Commits not commented: " + err.Error())
+		return
+	}
+	helper.SpinStopDisplay("success")
+	helper.SpinSideNoteDisplay(strconv.Itoa(len(commits)) + " commit(s) commented on " + wf.Ticket)
+}
@@ -6,6 +6,7 @@ package cmd
 import (
 	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -58,25 +59,47 @@ func useCommand(cmd *cobra.Command, args []string) {
 	log.Debug("run use")
 	helper.SpinStartDisplay("Git operations")
 
-	// Checkout
-	helper.SpinUpdateDisplay("git checkout " + workUseArg)
-	helper.RepoCheckout(workUseArg, RootRepo.PublicAuthKey)
-	// Pull
-	helper.SpinUpdateDisplay("Git pull")
-	pullInfo := helper.RepoPull(RootRepo.PublicAuthKey)
+	// Checkout, or open the workflow worktree in worktree mode
+	worktree := ""
+	created := false
+	var pullInfo string
+	if RootConfig.WorktreeEnabled {
+		refBranch, _ := helper.RepoGetWorkflowParam(workUseArg, helper.REFBRANCHPARAM)
+		worktree, created = openWorktree(workUseArg, refBranch)
+		helper.SpinUpdateDisplay("Git pull")
+		pullInfo = helper.RepoWorktreePull(worktree, RootRepo.PublicAuthKey)
+	} else {
+		helper.SpinUpdateDisplay("git checkout " + workUseArg)
+		helper.RepoCheckout(workUseArg, RootRepo.PublicAuthKey)
+		// Pull
+		helper.SpinUpdateDisplay("Git pull")
+		pullInfo = helper.RepoPull(RootRepo.PublicAuthKey)
+	}
 
-	// Set Current Workflow
+	// Set Current Workflow, the work interval of the previous one being closed
 	helper.SpinUpdateDisplay("Config update...")
+	if previous := helper.RepoConfigGetCurrentWorkflowName(); previous != "" && previous != workUseArg {
+		helper.RepoConfigStopWork(previous, time.Now())
+	}
 	helper.RepoConfigDefineCurrentWorkflow(workUseArg)
+	helper.RepoConfigStartWork(workUseArg, time.Now())
 	helper.RepoConfigWrite()
 
 	helper.SpinUpdateDisplay("Git operations")
 	helper.SpinStopDisplay("success")
 	helper.SpinSideNoteDisplay("Pull info: " + pullInfo)
+	if created {
+		helper.SpinSideNoteDisplay("Worktree created > " + worktree)
+	} else if worktree != "" {
+		helper.SpinSideNoteDisplay("Worktree > " + worktree)
+	}
 
 	latestWf := helper.RepoConfigGetCurrentWorkflow(workUseArg)
 
-	helper.ShowSummary(RootConfig, latestWf)
+	helper.ShowSummary(latestWf, workflowRefs(latestWf))
+	if worktree != "" {
+		cdInto(worktree)
+	}
 
 	// Say GoodBye
 	helper.ByeByeDisplay()
@@ -0,0 +1,59 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"os"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// openWorktree returns the worktree of the workflow branch, creating it when missing.
+// A branch missing locally starts from origin/<branch> once pushed, from refBranch otherwise.
+func openWorktree(branch, refBranch string) (string, bool) {
+	mainPath, worktrees, err := helper.RepoWorktrees()
+	if err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+	// Already checked out, the main worktree included for workflows started before worktree mode
+	if path, ok := worktrees[branch]; ok {
+		return path, false
+	}
+	path := helper.WorktreePath(RootConfig.WorktreeDir, mainPath, RootRepo.Name, branch)
+
+	startPoint := refBranch
+	if !helper.RepoHasBranch(branch) {
+		helper.SpinUpdateDisplay("git fetch")
+		if err := helper.RepoFetchOrigin(RootRepo.PublicAuthKey); err != nil {
+			helper.SpinStopDisplay("fail")
+			log.Fatalln(err)
+		}
+		if helper.RepoHasRemoteTrackingBranch(branch) {
+			startPoint = "origin/" + branch
+		} else if helper.RepoHasRemoteTrackingBranch(refBranch) {
+			startPoint = "origin/" + refBranch
+		}
+	}
+
+	helper.SpinUpdateDisplay("git worktree add " + path)
+	if err := helper.RepoWorktreeAdd(path, branch, startPoint); err != nil {
+		helper.SpinStopDisplay("fail")
+		log.Fatalln(err)
+	}
+	return path, true
+}
+
+// cdInto writes the directory the completion shell functions change into, with --cd-file
+func cdInto(path string) {
+	file := viper.GetString("cd_file")
+	if file == "" {
+		return
+	}
+	if err := os.WriteFile(file, []byte(path), 0600); err != nil {
+		log.Warningln("Directory to change into not written: " + err.Error())
+	}
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"net/http"
 	"regexp"
 
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
@@ -24,17 +25,60 @@ type Config struct {
 	CommitExpr       string
 	CommitTemplate   string
 	TypeMapping      string
+	// BranchTypeMapping maps a ticket type or label to a branch type (JSON object)
+	BranchTypeMapping string
 
 	Ticketing string
+	// Minutes fetched tickets are served from the local cache, 0 disabling the cache
+	TicketingCacheTTL int
 
 	TicketingJiraEnabled  bool
 	TicketingJiraServer   string
 	TicketingJiraUsername string
 	TicketingJiraPassword string
+	// Authentication method: "basic" (username/password), "pat" (Data Center token) or "cloud_token" (email + API token)
+	TicketingJiraAuth  string
+	TicketingJiraToken string
+	// TicketingJiraTransitions maps a project key (or "default") to the transition applied on each lifecycle event
+	TicketingJiraTransitions map[string]map[string]string
+	// Worklog posting on pause/end, time being rounded to TicketingJiraWorklogRound minutes
+	// Rounding options: "up", "down", "nearest"
+	TicketingJiraWorklogEnabled  bool
+	TicketingJiraWorklogRound    int
+	TicketingJiraWorklogRounding string
+	// Comment the pushed commits on the workflow ticket
+	TicketingJiraCommentOnPush bool
+	// JQL listing the tickets offered by `pick`
+	TicketingJiraPickJQL string
+	// Project the tickets created by `init --create-ticket` go to
+	TicketingJiraProject string
 
 	TicketingGlabEnabled bool
 	TicketingGlabServer  string
 	TicketingGlabToken   string
+	// Reviewers (usernames) and labels applied by `ready`, CODEOWNERS providing the reviewers when none are set
+	TicketingGlabReviewers   []string
+	TicketingGlabReadyLabels []string
+
+	TicketingGithubEnabled bool
+	TicketingGithubServer  string
+	TicketingGithubToken   string
+
+	TicketingGiteaEnabled bool
+	TicketingGiteaServer  string
+	TicketingGiteaToken   string
+
+	TicketingBitbucketEnabled bool
+	TicketingBitbucketServer  string
+	TicketingBitbucketToken   string
+
+	TicketingAzureEnabled      bool
+	TicketingAzureServer       string
+	TicketingAzureOrganization string
+	TicketingAzureProject      string
+	TicketingAzureToken        string
+	// TicketingAzureStates maps a lifecycle event to the work item state it sets
+	TicketingAzureStates map[string]string
 
 	HasSshKeyId bool
 	SshKeyId    string
@@ -46,6 +90,14 @@ type Config struct {
 	// Options: "disabled", "warning", "fatal", "interactive"
 	UncommittedFilesDetection string
 
+	// SyncStrategy is how `sync` updates the workflow branch: "rebase" (default) or "merge"
+	SyncStrategy string
+
+	// WorktreeEnabled gives each workflow its own git worktree instead of checking it out in the current one
+	WorktreeEnabled bool
+	// WorktreeDir is where the worktrees are created, <main worktree>.worktrees when empty
+	WorktreeDir string
+
 	// AI configuration
 	AIEnabled                 bool
 	AIProvider                string
@@ -61,11 +113,62 @@ type Config struct {
 	AIGoogleServiceAccountKey string
 }
 
+// CommitInfo describes a commit reported to a ticketing system
+type CommitInfo struct {
+	Hash    string
+	Subject string
+}
+
+// Pipeline describes the latest CI pipeline of a workflow branch
+type Pipeline struct {
+	ID         int
+	Status     string
+	WebURL     string
+	Stages     []PipelineStage
+	FailedJobs []PipelineJob
+}
+
+// PipelineStage holds the aggregated status of a pipeline stage
+type PipelineStage struct {
+	Name   string
+	Status string
+}
+
+// PipelineJob describes a pipeline job
+type PipelineJob struct {
+	Name   string
+	Stage  string
+	WebURL string
+}
+
+// Finished tells whether the pipeline reached a final status
+func (p Pipeline) Finished() bool {
+	switch p.Status {
+	case "success", "failed", "canceled", "skipped", "manual":
+		return true
+	}
+	return false
+}
+
+// Review describes the review state of a merge request
+type Review struct {
+	ID                    int
+	Draft                 bool
+	Approvals             int
+	ApprovalsRequired     int
+	UnresolvedDiscussions int
+	HasConflicts          bool
+	Mergeable             bool
+	MergeStatus           string
+	WebURL                string
+}
+
 type Workflow struct {
 	CurrentWork string
 	BranchType  string
 	CommitType  string
 	Issue       int
+	LinkedIssue int
 	Ticket      string
 	Title       string
 	Commit      string
@@ -90,24 +193,87 @@ type Repo struct {
 }
 
 type JiraConfig struct {
-	Server   string
-	Username string
-	Password string
+	Server      string
+	Auth        string
+	Username    string
+	Password    string
+	Token       string
+	PickJQL     string
+	Transitions map[string]map[string]string
+	// HTTPClient replaces the default client, e.g. to record or replay the API exchanges
+	HTTPClient *http.Client
 }
 
 type GlabConfig struct {
-	BaseUrl string
-	Token   string
+	BaseUrl    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+type GithubConfig struct {
+	Server     string
+	Token      string
+	HTTPClient *http.Client
+}
+
+type GiteaConfig struct {
+	Server     string
+	Token      string
+	HTTPClient *http.Client
+}
+
+type BitbucketConfig struct {
+	Server     string
+	Token      string
+	HTTPClient *http.Client
+}
+
+type AzureConfig struct {
+	Server       string
+	Organization string
+	Project      string
+	Token        string
+	States       map[string]string
+	HTTPClient   *http.Client
+}
+
+// TicketRef is a ticket reference a tracker attaches to a workflow
+type TicketRef struct {
+	Label string // Label rendered in the workflow summary
+	Param string // Option stored in the .git/config workflow subsection
+	Value string
 }
 
 const (
 	JIRA           = "JIRA"
 	GITLAB         = "GITLAB"
+	GITHUB         = "GITHUB"
+	GITEA          = "GITEA"
+	BITBUCKET      = "BITBUCKET"
+	AZURE          = "AZURE"
+	JIRAGITLAB     = JIRA + "+" + GITLAB
 	NOTGIVEN       = "notGiven"
 	NOTGIVENBRANCH = "notGivenBranch"
 	GOMASTER       = "go_master"
 
+	// Workflow lifecycle events tickets can be transitioned on
+	EventInit  = "init"
+	EventPause = "pause"
+	EventEnd   = "end"
+
+	// Jira authentication methods
+	JiraAuthBasic      = "basic"
+	JiraAuthPAT        = "pat"
+	JiraAuthCloudToken = "cloud_token"
+
+	// Strategies bringing a workflow branch up to date with its ref branch
+	SyncRebase = "rebase"
+	SyncMerge  = "merge"
+
 	// Default commit ignore patterns (regex)
 	DefaultCommitIgnorePattern1 = `out\.ya?ml$`
 	DefaultCommitIgnorePattern2 = `out\d+\.ya?ml$`
 )
+
+// Trackers lists the ticketing systems that can be enabled under `ticketing.<name>` in the config
+var Trackers = []string{JIRA, GITLAB, GITHUB, GITEA, BITBUCKET, AZURE}
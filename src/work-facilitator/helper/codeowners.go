@@ -0,0 +1,147 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// codeOwnersLocations lists where GitLab and GitHub look for the CODEOWNERS file, in order
+	codeOwnersLocations = []string{"CODEOWNERS", ".gitlab/CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS"}
+)
+
+// CodeOwnersRule is a CODEOWNERS line: a path pattern and its owners
+type CodeOwnersRule struct {
+	Pattern string
+	Owners  []string
+}
+
+// RepoCodeOwners reads the CODEOWNERS rules of the repository, none when there is no CODEOWNERS file
+func RepoCodeOwners(basePath string) ([]CodeOwnersRule, error) {
+	for _, location := range codeOwnersLocations {
+		content, err := os.ReadFile(filepath.Join(basePath, location))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		log.Debugln("CODEOWNERS found at " + location)
+		return ParseCodeOwners(string(content)), nil
+	}
+	return nil, nil
+}
+
+// ParseCodeOwners parses CODEOWNERS content, skipping comments and GitLab section headers.
+// Rules without owners are kept, they clear the ownership of the matching paths.
+func ParseCodeOwners(content string) []CodeOwnersRule {
+	var rules []CodeOwnersRule
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		rules = append(rules, CodeOwnersRule{Pattern: fields[0], Owners: fields[1:]})
+	}
+	return rules
+}
+
+// DefaultCodeOwners returns the owners of the catch-all `*` rule, the last one winning
+func DefaultCodeOwners(rules []CodeOwnersRule) []string {
+	var owners []string
+	for _, rule := range rules {
+		if rule.Pattern == "*" {
+			owners = rule.Owners
+		}
+	}
+	return owners
+}
+
+// MatchCodeOwners returns the owners of the files, in order of appearance.
+// As in GitLab and GitHub, the last rule matching a file wins.
+func MatchCodeOwners(rules []CodeOwnersRule, files []string) []string {
+	patterns := make([]*regexp.Regexp, len(rules))
+	for i, rule := range rules {
+		patterns[i] = codeOwnersPattern(rule.Pattern)
+	}
+
+	seen := map[string]bool{}
+	var owners []string
+	for _, file := range files {
+		for i := len(rules) - 1; i >= 0; i-- {
+			if !patterns[i].MatchString(file) {
+				continue
+			}
+			for _, owner := range rules[i].Owners {
+				if !seen[owner] {
+					seen[owner] = true
+					owners = append(owners, owner)
+				}
+			}
+			break
+		}
+	}
+	return owners
+}
+
+// codeOwnersPattern turns a gitignore style CODEOWNERS pattern into a regexp matching file paths.
+// Patterns with a leading or inner `/` are anchored to the repository root, a pattern matching
+// a directory matches all the files below it.
+func codeOwnersPattern(pattern string) *regexp.Regexp {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var expr strings.Builder
+	if !anchored {
+		expr.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	if dirOnly {
+		expr.WriteString("/.*")
+	} else {
+		expr.WriteString("(/.*)?")
+	}
+
+	return regexp.MustCompile("^" + expr.String() + "$")
+}
+
+// CodeOwnersUsernames keeps the `@user` owners, without the `@`.
+// Groups (`@group/subgroup`) and emails cannot be requested as reviewers.
+func CodeOwnersUsernames(owners []string) []string {
+	var usernames []string
+	for _, owner := range owners {
+		if !strings.HasPrefix(owner, "@") || strings.Contains(owner, "/") {
+			continue
+		}
+		usernames = append(usernames, strings.TrimPrefix(owner, "@"))
+	}
+	return usernames
+}
@@ -0,0 +1,141 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCodeOwners(t *testing.T) {
+	content := `# Default owners
+* @alice @team/backend
+
+[Documentation]
+docs/ @bob # writers
+^[Optional]
+/vendor/
+`
+
+	expected := []CodeOwnersRule{
+		{Pattern: "*", Owners: []string{"@alice", "@team/backend"}},
+		{Pattern: "docs/", Owners: []string{"@bob"}},
+		{Pattern: "/vendor/", Owners: []string{}},
+	}
+
+	result := ParseCodeOwners(content)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("ParseCodeOwners() = %+v, want %+v", result, expected)
+	}
+}
+
+func TestRepoCodeOwners(t *testing.T) {
+	dir := t.TempDir()
+
+	rules, err := RepoCodeOwners(dir)
+	if err != nil || rules != nil {
+		t.Fatalf("RepoCodeOwners() = %v, %v, want no rules", rules, err)
+	}
+
+	os.MkdirAll(filepath.Join(dir, ".gitlab"), 0755)
+	os.WriteFile(filepath.Join(dir, ".gitlab", "CODEOWNERS"), []byte("* @alice\n"), 0644)
+
+	rules, err = RepoCodeOwners(dir)
+	if err != nil {
+		t.Fatalf("RepoCodeOwners() unexpected error: %v", err)
+	}
+	if owners := DefaultCodeOwners(rules); !reflect.DeepEqual(owners, []string{"@alice"}) {
+		t.Errorf("DefaultCodeOwners() = %v, want [@alice]", owners)
+	}
+}
+
+func TestMatchCodeOwners(t *testing.T) {
+	rules := ParseCodeOwners(`* @alice
+*.go @gophers/team
+/docs/ @bob
+src/**/api/ @carol
+/vendor/
+`)
+
+	tests := []struct {
+		name     string
+		files    []string
+		expected []string
+	}{
+		{
+			name:     "catch-all",
+			files:    []string{"README.md"},
+			expected: []string{"@alice"},
+		},
+		{
+			name:     "extension at any depth",
+			files:    []string{"cmd/main.go"},
+			expected: []string{"@gophers/team"},
+		},
+		{
+			name:     "anchored directory",
+			files:    []string{"docs/guide/intro.md", "site/docs/index.md"},
+			expected: []string{"@bob", "@alice"},
+		},
+		{
+			name:     "double star, last match wins",
+			files:    []string{"src/v1/api/handler.go", "src/api/routes.go"},
+			expected: []string{"@carol"},
+		},
+		{
+			name:     "ownership cleared",
+			files:    []string{"vendor/lib/lib.go"},
+			expected: nil,
+		},
+		{
+			name:     "no files",
+			files:    nil,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := MatchCodeOwners(rules, tt.files)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("MatchCodeOwners() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCodeOwnersUsernames(t *testing.T) {
+	tests := []struct {
+		name     string
+		owners   []string
+		expected []string
+	}{
+		{
+			name:     "users only",
+			owners:   []string{"@alice", "@bob"},
+			expected: []string{"alice", "bob"},
+		},
+		{
+			name:     "groups and emails skipped",
+			owners:   []string{"@team/backend", "carol@example.com", "@dave"},
+			expected: []string{"dave"},
+		},
+		{
+			name:     "no owners",
+			owners:   nil,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CodeOwnersUsernames(tt.owners)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("CodeOwnersUsernames() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -37,20 +37,53 @@ func NewConfig() c.Config {
 	ticketingJiraServer := viper.GetString("ticketing.jira.server")
 	ticketingJiraUsername := viper.GetString("ticketing.jira.username")
 	ticketingJiraPassword := viper.GetString("ticketing.jira.password")
+	ticketingJiraAuth := viper.GetString("ticketing.jira.auth")
+	if ticketingJiraAuth == "" {
+		ticketingJiraAuth = c.JiraAuthBasic // Default to username/password
+	}
+	ticketingJiraToken := viper.GetString("ticketing.jira.token")
+	ticketingJiraTransitions := map[string]map[string]string{}
+	for project := range viper.GetStringMap("ticketing.jira.transitions") {
+		ticketingJiraTransitions[project] = viper.GetStringMapString("ticketing.jira.transitions." + project)
+	}
+	ticketingJiraWorklogEnabled := viper.GetBool("ticketing.jira.worklog.enabled")
+	ticketingJiraCommentOnPush := viper.GetBool("ticketing.jira.comment_on_push")
+	ticketingJiraPickJQL := viper.GetString("ticketing.jira.pick_jql")
+	ticketingJiraProject := viper.GetString("ticketing.jira.project")
+	ticketingJiraWorklogRound := viper.GetInt("ticketing.jira.worklog.round_minutes")
+	ticketingJiraWorklogRounding := viper.GetString("ticketing.jira.worklog.rounding")
+	if ticketingJiraWorklogRounding == "" {
+		ticketingJiraWorklogRounding = RoundingUp // Default to round up
+	}
+	if ticketingJiraWorklogRounding != RoundingUp && ticketingJiraWorklogRounding != RoundingDown && ticketingJiraWorklogRounding != RoundingNearest {
+		log.Warningln("Invalid ticketing.jira.worklog.rounding value: " + ticketingJiraWorklogRounding + ". Using 'up' as default.")
+		ticketingJiraWorklogRounding = RoundingUp
+	}
 	ticketingGlabEnabled := viper.GetBool("ticketing.gitlab.enabled")
 	ticketingGlabServer := viper.GetString("ticketing.gitlab.server")
 	ticketingGlabToken := viper.GetString("ticketing.gitlab.token")
-
-	ticketing := ""
-	if ticketingJiraEnabled && ticketingGlabEnabled {
-		log.Fatalln("Both Jira and Gitlab defined. Unknow behaviour")
-	}
-	if ticketingJiraEnabled {
-		ticketing = c.JIRA
-	} else if ticketingGlabEnabled {
-		ticketing = c.GITLAB
-	} else {
-		log.Warningln("No ticketing system defined. Unknow behaviour")
+	ticketingGlabReviewers := viper.GetStringSlice("ticketing.gitlab.reviewers")
+	ticketingGlabReadyLabels := viper.GetStringSlice("ticketing.gitlab.ready_labels")
+	ticketingGithubEnabled := viper.GetBool("ticketing.github.enabled")
+	ticketingGithubServer := viper.GetString("ticketing.github.server")
+	ticketingGithubToken := viper.GetString("ticketing.github.token")
+	ticketingGiteaEnabled := viper.GetBool("ticketing.gitea.enabled")
+	ticketingGiteaServer := viper.GetString("ticketing.gitea.server")
+	ticketingGiteaToken := viper.GetString("ticketing.gitea.token")
+	ticketingBitbucketEnabled := viper.GetBool("ticketing.bitbucket.enabled")
+	ticketingBitbucketServer := viper.GetString("ticketing.bitbucket.server")
+	ticketingBitbucketToken := viper.GetString("ticketing.bitbucket.token")
+	ticketingAzureEnabled := viper.GetBool("ticketing.azure.enabled")
+	ticketingAzureServer := viper.GetString("ticketing.azure.server")
+	ticketingAzureOrganization := viper.GetString("ticketing.azure.organization")
+	ticketingAzureProject := viper.GetString("ticketing.azure.project")
+	ticketingAzureToken := viper.GetString("ticketing.azure.token")
+	ticketingAzureStates := viper.GetStringMapString("ticketing.azure.states")
+
+	ticketing := defineTicketing()
+	ticketingCacheTTL := 0 // Default to no cache, tickets being fetched each time
+	if viper.IsSet("ticketing.cache_ttl_minutes") {
+		ticketingCacheTTL = viper.GetInt("ticketing.cache_ttl_minutes")
 	}
 
 	sshKeyId := viper.GetString("global.ssh_key_id")
@@ -94,6 +127,20 @@ func NewConfig() c.Config {
 		uncommittedFilesDetection = "fatal"
 	}
 
+	// Load sync strategy (with default)
+	syncStrategy := viper.GetString("global.sync_strategy")
+	if syncStrategy == "" {
+		syncStrategy = c.SyncRebase // Default to rebase
+	}
+	if syncStrategy != c.SyncRebase && syncStrategy != c.SyncMerge {
+		log.Warningln("Invalid sync_strategy value: " + syncStrategy + ". Using 'rebase' as default.")
+		syncStrategy = c.SyncRebase
+	}
+
+	// Load worktree mode (opt-in)
+	worktreeEnabled := viper.GetBool("global.worktree.enabled")
+	worktreeDir := viper.GetString("global.worktree.dir")
+
 	// Load AI configuration
 	aiEnabled := viper.GetBool("ai.enabled")
 	aiProvider := viper.GetString("ai.provider")
@@ -152,19 +199,50 @@ func NewConfig() c.Config {
 		CommitExpr:                   viper.GetString("global.commit_expr"),
 		CommitTemplate:               viper.GetString("global.commit_template"),
 		TypeMapping:                  typeMapping,
+		BranchTypeMapping:            viper.GetString("global.branch_type_mapping"),
 		Ticketing:                    ticketing,
+		TicketingCacheTTL:            ticketingCacheTTL,
 		TicketingJiraEnabled:         ticketingJiraEnabled,
 		TicketingJiraServer:          ticketingJiraServer,
 		TicketingJiraUsername:        ticketingJiraUsername,
 		TicketingJiraPassword:        ticketingJiraPassword,
+		TicketingJiraAuth:            ticketingJiraAuth,
+		TicketingJiraToken:           ticketingJiraToken,
+		TicketingJiraTransitions:     ticketingJiraTransitions,
+		TicketingJiraWorklogEnabled:  ticketingJiraWorklogEnabled,
+		TicketingJiraWorklogRound:    ticketingJiraWorklogRound,
+		TicketingJiraWorklogRounding: ticketingJiraWorklogRounding,
+		TicketingJiraCommentOnPush:   ticketingJiraCommentOnPush,
+		TicketingJiraPickJQL:         ticketingJiraPickJQL,
+		TicketingJiraProject:         ticketingJiraProject,
 		TicketingGlabEnabled:         ticketingGlabEnabled,
 		TicketingGlabServer:          ticketingGlabServer,
 		TicketingGlabToken:           ticketingGlabToken,
+		TicketingGlabReviewers:       ticketingGlabReviewers,
+		TicketingGlabReadyLabels:     ticketingGlabReadyLabels,
+		TicketingGithubEnabled:       ticketingGithubEnabled,
+		TicketingGithubServer:        ticketingGithubServer,
+		TicketingGithubToken:         ticketingGithubToken,
+		TicketingGiteaEnabled:        ticketingGiteaEnabled,
+		TicketingGiteaServer:         ticketingGiteaServer,
+		TicketingGiteaToken:          ticketingGiteaToken,
+		TicketingBitbucketEnabled:    ticketingBitbucketEnabled,
+		TicketingBitbucketServer:     ticketingBitbucketServer,
+		TicketingBitbucketToken:      ticketingBitbucketToken,
+		TicketingAzureEnabled:        ticketingAzureEnabled,
+		TicketingAzureServer:         ticketingAzureServer,
+		TicketingAzureOrganization:   ticketingAzureOrganization,
+		TicketingAzureProject:        ticketingAzureProject,
+		TicketingAzureToken:          ticketingAzureToken,
+		TicketingAzureStates:         ticketingAzureStates,
 		HasSshKeyId:                  hasSshKeyId,
 		SshKeyId:                     sshKeyId,
 		CommitIgnorePatterns:         commitIgnorePatterns,
 		CommitIgnorePatternsCompiled: commitIgnorePatternsCompiled,
 		UncommittedFilesDetection:    uncommittedFilesDetection,
+		SyncStrategy:                 syncStrategy,
+		WorktreeEnabled:              worktreeEnabled,
+		WorktreeDir:                  worktreeDir,
 		AIEnabled:                    aiEnabled,
 		AIProvider:                   aiProvider,
 		AIAPIKey:                     aiAPIKey,
@@ -182,6 +260,29 @@ func NewConfig() c.Config {
 	return conf
 }
 
+// defineTicketing returns the single ticketing system enabled under `ticketing.<name>.enabled`
+func defineTicketing() string {
+	var enabled []string
+	for _, t := range c.Trackers {
+		if viper.GetBool("ticketing." + strings.ToLower(t) + ".enabled") {
+			enabled = append(enabled, t)
+		}
+	}
+
+	// Jira for tickets and GitLab for merge requests work together
+	if len(enabled) == 2 && enabled[0] == c.JIRA && enabled[1] == c.GITLAB {
+		return c.JIRAGITLAB
+	}
+	if len(enabled) > 1 {
+		log.Fatalln("Several ticketing systems defined (" + strings.Join(enabled, ", ") + "). Unknow behaviour")
+	}
+	if len(enabled) == 0 {
+		log.Warningln("No ticketing system defined. Unknow behaviour")
+		return ""
+	}
+	return enabled[0]
+}
+
 func QuickConfig() (string, string) {
 
 	initConfig()
@@ -308,6 +409,42 @@ func DefineCommit(branchType string, typeMapping string) string {
 	return commit.(string)
 }
 
+// DefineBranchType infers the branch type of a ticket from its type, then from its labels.
+// It returns the branch type and the applied rule, both empty when nothing matches.
+func DefineBranchType(ticketType string, labels []string, branchTypeMapping string) (string, string) {
+	if branchTypeMapping == "" {
+		return "", ""
+	}
+
+	var jsonMap map[string]string
+	if err := json.Unmarshal([]byte(branchTypeMapping), &jsonMap); err != nil {
+		log.Warningln("Invalid global.branch_type_mapping: " + err.Error())
+		return "", ""
+	}
+	log.Debugln("Branch type mapping: " + branchTypeMapping)
+
+	// Keys are matched case-insensitively, ticket types and labels casing varies across trackers
+	lookup := func(value string) string {
+		for key, branchType := range jsonMap {
+			if strings.EqualFold(key, value) {
+				return branchType
+			}
+		}
+		return ""
+	}
+
+	if branchType := lookup(ticketType); ticketType != "" && branchType != "" {
+		return branchType, "type " + ticketType + " -> " + branchType
+	}
+	for _, label := range labels {
+		if branchType := lookup(label); branchType != "" {
+			return branchType, "label " + label + " -> " + branchType
+		}
+	}
+
+	return "", ""
+}
+
 func TestStandard(commit, commitExpr, branch, branchExpr string, standardEnforced bool) bool {
 	ok := true
 	if standardEnforced {
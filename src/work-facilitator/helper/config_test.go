@@ -4,7 +4,10 @@ Copyright © 2024 Jean Bordat bordat.jean@gmail.com
 package helper
 
 import (
+	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"testing"
+
+	"github.com/spf13/viper"
 )
 
 func TestTestStandard(t *testing.T) {
@@ -226,3 +229,127 @@ func TestDefineCommit(t *testing.T) {
 		})
 	}
 }
+
+func TestDefineTicketing(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  []string
+		expected string
+	}{
+		{
+			name:     "none enabled",
+			expected: "",
+		},
+		{
+			name:     "jira",
+			enabled:  []string{"jira"},
+			expected: c.JIRA,
+		},
+		{
+			name:     "gitea",
+			enabled:  []string{"gitea"},
+			expected: c.GITEA,
+		},
+		{
+			name:     "bitbucket",
+			enabled:  []string{"bitbucket"},
+			expected: c.BITBUCKET,
+		},
+		{
+			name:     "azure",
+			enabled:  []string{"azure"},
+			expected: c.AZURE,
+		},
+		{
+			name:     "jira and gitlab combined",
+			enabled:  []string{"gitlab", "jira"},
+			expected: c.JIRAGITLAB,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			for _, tracker := range tt.enabled {
+				viper.Set("ticketing."+tracker+".enabled", true)
+			}
+
+			result := defineTicketing()
+			if result != tt.expected {
+				t.Errorf("defineTicketing() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDefineBranchType(t *testing.T) {
+	mapping := `{"Bug": "fix", "Story": "feat", "feature": "feat", "hotfix": "fix"}`
+
+	tests := []struct {
+		name         string
+		ticketType   string
+		labels       []string
+		mapping      string
+		expected     string
+		expectedRule string
+	}{
+		{
+			name:         "ticket type",
+			ticketType:   "Bug",
+			mapping:      mapping,
+			expected:     "fix",
+			expectedRule: "type Bug -> fix",
+		},
+		{
+			name:         "ticket type is case insensitive",
+			ticketType:   "story",
+			mapping:      mapping,
+			expected:     "feat",
+			expectedRule: "type story -> feat",
+		},
+		{
+			name:         "type wins over labels",
+			ticketType:   "Story",
+			labels:       []string{"hotfix"},
+			mapping:      mapping,
+			expected:     "feat",
+			expectedRule: "type Story -> feat",
+		},
+		{
+			name:         "first matching label",
+			ticketType:   "Task",
+			labels:       []string{"backend", "Feature", "hotfix"},
+			mapping:      mapping,
+			expected:     "feat",
+			expectedRule: "label Feature -> feat",
+		},
+		{
+			name:       "no match",
+			ticketType: "Task",
+			labels:     []string{"backend"},
+			mapping:    mapping,
+		},
+		{
+			name:       "no mapping",
+			ticketType: "Bug",
+		},
+		{
+			name:       "invalid mapping",
+			ticketType: "Bug",
+			mapping:    `{"Bug": `,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, rule := DefineBranchType(tt.ticketType, tt.labels, tt.mapping)
+			if result != tt.expected {
+				t.Errorf("DefineBranchType() = %v, want %v", result, tt.expected)
+			}
+			if rule != tt.expectedRule {
+				t.Errorf("DefineBranchType() rule = %v, want %v", rule, tt.expectedRule)
+			}
+		})
+	}
+}
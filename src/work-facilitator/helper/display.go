@@ -2,7 +2,6 @@ package helper
 
 import (
 	c "spirit-dev/work-facilitator/work-facilitator/common"
-	"strconv"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/pterm/pterm"
@@ -70,21 +69,81 @@ func SpinStopDisplay(resolved string) {
 	}
 }
 
-func ShowSummary(cfg c.Config, wf c.Workflow) {
-	dt, title := "", ""
+func ShowSummary(wf c.Workflow, refs []c.TicketRef) {
+	title := "branch type\ncommit type\ntitle"
+	dt := wf.BranchType + "\n" + wf.CommitType + "\n" + wf.Title
+	for _, ref := range refs {
+		title += "\n" + ref.Label
+		dt += "\n" + ref.Value
+	}
+	title += "\nbranch\ncommit\nref branch"
+	dt += "\n" + wf.Branch + "\n" + wf.Commit + "\n" + wf.RefBranch
 
-	if cfg.Ticketing == c.JIRA {
-		title = "branch type\ncommit type\ntitle\nticket\nbranch\ncommit\nref branch"
-		dt = wf.BranchType + "\n" + wf.CommitType + "\n" + wf.Title + "\n" + wf.Ticket + "\n" + wf.Branch + "\n" + wf.Commit + "\n" + wf.RefBranch
+	panels := pterm.Panels{
+		{
+			{Data: pterm.DefaultBox.WithRightPadding(10).WithLeftPadding(10).Sprintf(wf.Branch)},
+		},
+		{
+			{Data: pterm.Cyan(title)},
+			{Data: dt},
+		},
 	}
-	if cfg.Ticketing == c.GITLAB {
-		title = "branch type\ncommit type\ntitle\nissue\nbranch\ncommit\nref branch"
-		dt = wf.BranchType + "\n" + wf.CommitType + "\n" + wf.Title + "\n" + strconv.Itoa(wf.Issue) + "\n" + wf.Branch + "\n" + wf.Commit + "\n" + wf.RefBranch
+
+	pterm.DefaultPanel.WithPanels(panels).WithPadding(15).Render()
+}
+
+// ShowReview renders the review state of the workflow merge request
+func ShowReview(review c.Review) {
+	title := "draft\napprovals\nunresolved threads\nconflicts\nmergeable\nurl"
+	dt := pterm.Sprintf("%s\n%d/%d\n%d\n%s\n%s\n%s",
+		yesNo(review.Draft, false),
+		review.Approvals, review.ApprovalsRequired,
+		review.UnresolvedDiscussions,
+		yesNo(review.HasConflicts, false),
+		yesNo(review.Mergeable, true)+" ("+review.MergeStatus+")",
+		review.WebURL)
+
+	panels := pterm.Panels{
+		{
+			{Data: pterm.DefaultBox.WithRightPadding(10).WithLeftPadding(10).Sprintf("merge request !%d", review.ID)},
+		},
+		{
+			{Data: pterm.Cyan(title)},
+			{Data: dt},
+		},
+	}
+
+	pterm.DefaultPanel.WithPanels(panels).WithPadding(15).Render()
+}
+
+// yesNo renders a flag, in green when it has the wanted value
+func yesNo(flag, wanted bool) string {
+	text := "no"
+	if flag {
+		text = "yes"
+	}
+	if flag == wanted {
+		return pterm.Green(text)
+	}
+	return pterm.Red(text)
+}
+
+// ShowPipeline renders the CI pipeline of the workflow branch
+func ShowPipeline(pipeline c.Pipeline) {
+	title := "status\nurl"
+	dt := pipelineStatus(pipeline.Status) + "\n" + pipeline.WebURL
+	for _, stage := range pipeline.Stages {
+		title += "\nstage " + stage.Name
+		dt += "\n" + pipelineStatus(stage.Status)
+	}
+	for _, job := range pipeline.FailedJobs {
+		title += "\nfailed job"
+		dt += "\n" + job.Stage + " > " + job.Name + " " + job.WebURL
 	}
 
 	panels := pterm.Panels{
 		{
-			{Data: pterm.DefaultBox.WithRightPadding(10).WithLeftPadding(10).Sprintf(wf.Branch)},
+			{Data: pterm.DefaultBox.WithRightPadding(10).WithLeftPadding(10).Sprintf("pipeline #%d", pipeline.ID)},
 		},
 		{
 			{Data: pterm.Cyan(title)},
@@ -95,6 +154,26 @@ func ShowSummary(cfg c.Config, wf c.Workflow) {
 	pterm.DefaultPanel.WithPanels(panels).WithPadding(15).Render()
 }
 
+// pipelineStatus colours a pipeline or stage status
+func pipelineStatus(status string) string {
+	switch status {
+	case "success":
+		return pterm.Green(status)
+	case "failed":
+		return pterm.Red(status)
+	case "running", "pending", "created":
+		return pterm.Yellow(status)
+	}
+	return pterm.Gray(status)
+}
+
+// ShowTable renders the rows under a header line
+func ShowTable(header []string, rows [][]string) {
+	data := pterm.TableData{header}
+	data = append(data, rows...)
+	pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+}
+
 func ShowBox(status git.Status) {
 	pterm.DefaultBox.WithRightPadding(5).WithLeftPadding(5).Println("changes")
 	Addline(status.String() + "\n")
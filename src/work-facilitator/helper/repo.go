@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"strconv"
 	"strings"
@@ -17,6 +19,8 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	log "github.com/sirupsen/logrus"
 )
@@ -41,8 +45,9 @@ const (
 	currentParam             = "current"
 	typeBranchParam          = "type-branch"
 	typeCommitParam          = "type-commit"
-	mrRefParam               = "mrref"
-	ticketParam              = "ticket"
+	MRREFPARAM               = "mrref"
+	ISSUEREFPARAM            = "issueref"
+	TICKETPARAM              = "ticket"
 	titleParam               = "title"
 	branchParm               = "branch"
 	commitParam              = "commit"
@@ -118,6 +123,13 @@ func NewRepo(wfConfig c.Config) c.Repo {
 
 	// Build browser url based on origin url
 	browserUrl := "https://" + gitRepoHost + "/" + gitRepoNs + "/" + gitRepoName
+	// Bitbucket Server clones through /scm/ (http) or a dedicated ssh port, and browses projects elsewhere,
+	// whichever ticketing tracks the work
+	if BitbucketRemote(repoParsedUrl.Path, repoParsedUrl.Port(), gitRepoHost, wfConfig.TicketingBitbucketServer) {
+		gitRepoNs = BitbucketProject(gitRepoNs)
+		gitRepoFName = gitRepoNs + "/" + gitRepoName
+		browserUrl = BitbucketBrowserURL(wfConfig.TicketingBitbucketServer, gitRepoHost, gitRepoNs, gitRepoName)
+	}
 	log.Debugln("Browser url: " + browserUrl)
 
 	// Repo default branch
@@ -150,6 +162,13 @@ func NewRepo(wfConfig c.Config) c.Repo {
 	if err != nil || currentWf == "" {
 		hasCurrentWf = false
 	}
+	// In worktree mode, the current workflow is the one checked out in this worktree
+	if wfConfig.WorktreeEnabled {
+		currentWf, hasCurrentWf = "", false
+		if head, errH := repo.Head(); errH == nil && head.Name().IsBranch() && WorkflowExisting(head.Name().Short()) {
+			currentWf, hasCurrentWf = head.Name().Short(), true
+		}
+	}
 
 	var currentWfData c.Workflow
 	if hasCurrentWf {
@@ -176,13 +195,15 @@ func NewRepo(wfConfig c.Config) c.Repo {
 }
 
 func RepoConfigGetCurrentWorkflow(currentWf string) c.Workflow {
-	issue, _ := strconv.Atoi(repoGetWorkflowParam(currentWf, mrRefParam))
+	issue, _ := strconv.Atoi(repoGetWorkflowParam(currentWf, MRREFPARAM))
+	linkedIssue, _ := strconv.Atoi(repoGetWorkflowParam(currentWf, ISSUEREFPARAM))
 	return c.Workflow{
 		CurrentWork: currentWf,
 		BranchType:  repoGetWorkflowParam(currentWf, typeBranchParam),
 		CommitType:  repoGetWorkflowParam(currentWf, typeCommitParam),
 		Issue:       issue,
-		Ticket:      repoGetWorkflowParam(currentWf, ticketParam),
+		LinkedIssue: linkedIssue,
+		Ticket:      repoGetWorkflowParam(currentWf, TICKETPARAM),
 		Title:       repoGetWorkflowParam(currentWf, titleParam),
 		Commit:      repoGetWorkflowParam(currentWf, commitParam),
 		RefBranch:   repoGetWorkflowParam(currentWf, REFBRANCHPARAM),
@@ -190,11 +211,17 @@ func RepoConfigGetCurrentWorkflow(currentWf string) c.Workflow {
 	}
 }
 
+// RepoConfigGetCurrentWorkflowName returns the workflow set as current in the config, the last one used in worktree mode
+func RepoConfigGetCurrentWorkflowName() string {
+	currentWf, _ := repoConfigGetParam(wfsetupSection, currentParam)
+	return currentWf
+}
+
 func RepoConfigDefineCurrentWorkflow(workflow string) {
 	repoConfigUpdateParam(wfsetupSection, currentParam, workflow)
 }
 
-func RepoConfigDefineWorkflow(rootCfg c.Config, wf c.Workflow) {
+func RepoConfigDefineWorkflow(wf c.Workflow, refs []c.TicketRef) {
 	// Set the current work flow
 	RepoConfigDefineCurrentWorkflow(wf.CurrentWork)
 
@@ -205,13 +232,9 @@ func RepoConfigDefineWorkflow(rootCfg c.Config, wf c.Workflow) {
 	repoConfigAddSubSectParam(wfSection, wf.CurrentWork, commitParam, wf.Commit)
 	repoConfigAddSubSectParam(wfSection, wf.CurrentWork, REFBRANCHPARAM, wf.RefBranch)
 
-	// Set workflow variables
-	if rootCfg.Ticketing == c.GITLAB {
-		repoConfigAddSubSectParam(wfSection, wf.CurrentWork, mrRefParam, strconv.Itoa(wf.Issue))
-
-	}
-	if rootCfg.Ticketing == c.JIRA {
-		repoConfigAddSubSectParam(wfSection, wf.CurrentWork, ticketParam, wf.Ticket)
+	// Set ticketing variables
+	for _, ref := range refs {
+		repoConfigAddSubSectParam(wfSection, wf.CurrentWork, ref.Param, ref.Value)
 	}
 
 	// Set Branch variables
@@ -224,6 +247,14 @@ func RepoConfigDefineWorkflow(rootCfg c.Config, wf c.Workflow) {
 	repoConfigAddSubSectParam(branchSection, wf.CurrentWork, vscodeMergeBaseParam, fmt.Sprintf("origin/%s", wf.CurrentWork)) // Not sure this one works
 }
 
+// RepoConfigUpdateWorkflowTicket updates the commit prefix and the ticket references of a defined workflow
+func RepoConfigUpdateWorkflowTicket(wf c.Workflow, refs []c.TicketRef) {
+	repoConfigSetSubSectParam(wfSection, wf.CurrentWork, commitParam, wf.Commit)
+	for _, ref := range refs {
+		repoConfigSetSubSectParam(wfSection, wf.CurrentWork, ref.Param, ref.Value)
+	}
+}
+
 func RepoConfigWrite() {
 	err := repo.SetConfig(repoCfg)
 	if err != nil {
@@ -294,50 +325,7 @@ func RepoCheckout(branch string, pubKey *ssh.PublicKeys) {
 }
 
 func RepoPull(pubKey *ssh.PublicKeys) string {
-	// Get the working directory for the repository
-	w, err := repo.Worktree()
-	if err != nil {
-		if !Quiet {
-			SpinStopDisplay("fail")
-		}
-		log.Fatalln(err)
-	}
-
-	opts := &git.PullOptions{
-		RemoteName: originValue,
-		Force:      true,
-	}
-	if pubKey != nil {
-		opts.Auth = pubKey
-	}
-
-	var info string
-	// Pull the latest changes from the origin remote and merge into the current branch
-	log.Debugln("git pull origin")
-	err = w.Pull(opts)
-	if err != nil {
-		log.Debugln(err)
-		info = err.Error()
-	}
-
-	// Print the latest commit that was just pulled
-	ref, err := repo.Head()
-	if err != nil {
-		if !Quiet {
-			SpinStopDisplay("fail")
-		}
-		log.Fatalln(err)
-	}
-	commit, err := repo.CommitObject(ref.Hash())
-	if err != nil {
-		if !Quiet {
-			SpinStopDisplay("fail")
-		}
-		log.Fatalln(err)
-	}
-
-	log.Debugln(commit)
-	return info
+	return pull(repo, pubKey)
 }
 
 func RepoGetWorkflowParam(subsection, param string) (string, error) {
@@ -593,6 +581,242 @@ func RepoPush(pubKey *ssh.PublicKeys, branch string) {
 	}
 }
 
+// RepoDeleteRemoteBranch deletes the branch on origin, a branch already gone is not an error
+func RepoDeleteRemoteBranch(pubKey *ssh.PublicKeys, branch string) error {
+	log.Debugln("git push origin --delete " + branch)
+
+	refSpec := config.RefSpec(fmt.Sprintf(":refs/heads/%s", branch))
+	opts := &git.PushOptions{
+		RemoteName: originValue,
+		RefSpecs:   []config.RefSpec{refSpec},
+	}
+	if pubKey != nil {
+		opts.Auth = pubKey
+	}
+
+	err := repo.Push(opts)
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	return nil
+}
+
+// maxUnpushedCommits bounds the commits reported for a single push
+const maxUnpushedCommits = 20
+
+// RepoUnpushedCommits lists the commits of the branch not on origin yet, newest first.
+// The walk stops at origin/<branch>, or at origin/<refBranch> for a branch never pushed:
+// their merge bases with the branch are computed once, and the walk does not go past them.
+func RepoUnpushedCommits(branch, refBranch string) ([]c.CommitInfo, error) {
+	head, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return nil, err
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	pushed := map[plumbing.Hash]bool{}
+	for _, b := range []string{branch, refBranch} {
+		ref, errR := repo.Reference(plumbing.NewRemoteReferenceName(originValue, b), true)
+		if errR != nil {
+			continue
+		}
+		stop, errC := repo.CommitObject(ref.Hash())
+		if errC != nil {
+			continue
+		}
+		bases, errB := headCommit.MergeBase(stop)
+		if errB != nil {
+			return nil, errB
+		}
+		for _, base := range bases {
+			pushed[base.Hash] = true
+		}
+	}
+
+	isUnpushed := object.CommitFilter(func(commit *object.Commit) bool { return !pushed[commit.Hash] })
+	isPushed := object.CommitFilter(func(commit *object.Commit) bool { return pushed[commit.Hash] })
+	iter := object.NewFilterCommitIter(headCommit, &isUnpushed, &isPushed)
+
+	var commits []c.CommitInfo
+	err = iter.ForEach(func(commit *object.Commit) error {
+		commits = append(commits, c.CommitInfo{
+			Hash:    commit.Hash.String(),
+			Subject: strings.SplitN(strings.TrimSpace(commit.Message), "\n", 2)[0],
+		})
+		if len(commits) >= maxUnpushedCommits {
+			return storer.ErrStop
+		}
+		return nil
+	})
+
+	return commits, err
+}
+
+// RepoFetchOrigin fetches origin with its configured refspecs
+func RepoFetchOrigin(pubKey *ssh.PublicKeys) error {
+	return fetchOrigin("", pubKey)
+}
+
+// RepoRemoteBranches lists the branches currently present on origin
+func RepoRemoteBranches(pubKey *ssh.PublicKeys) (map[string]bool, error) {
+	remote, err := repo.Remote(originValue)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &git.ListOptions{}
+	if pubKey != nil {
+		opts.Auth = pubKey
+	}
+	refs, err := remote.List(opts)
+	if err != nil {
+		return nil, fmt.Errorf("list origin failed: %v", err)
+	}
+
+	branches := map[string]bool{}
+	for _, ref := range refs {
+		if ref.Name().IsBranch() {
+			branches[ref.Name().Short()] = true
+		}
+	}
+	return branches, nil
+}
+
+// RepoHasBranch tells whether the local branch exists
+func RepoHasBranch(branch string) bool {
+	return branchExists(branch)
+}
+
+// RepoHasRemoteTrackingBranch tells whether origin/<branch> is known locally, i.e. the branch has been pushed
+func RepoHasRemoteTrackingBranch(branch string) bool {
+	_, err := repo.Reference(plumbing.NewRemoteReferenceName(originValue, branch), false)
+	return err == nil
+}
+
+// RepoBranchMerged tells whether the head of the local branch is reachable from origin/<into>
+func RepoBranchMerged(branch, into string) (bool, error) {
+	head, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return false, err
+	}
+	target, err := repo.Reference(plumbing.NewRemoteReferenceName(originValue, into), true)
+	if err != nil {
+		return false, err
+	}
+
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return false, err
+	}
+	targetCommit, err := repo.CommitObject(target.Hash())
+	if err != nil {
+		return false, err
+	}
+
+	return headCommit.IsAncestor(targetCommit)
+}
+
+// RepoChangedFiles returns the files changed on the branch since it forked from the reference branch,
+// the remote reference branch being preferred to the local one
+func RepoChangedFiles(branch, refBranch string) ([]string, error) {
+	head, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := repo.Reference(plumbing.NewRemoteReferenceName(originValue, refBranch), true)
+	if err != nil {
+		if ref, err = repo.Reference(plumbing.NewBranchReferenceName(refBranch), true); err != nil {
+			return nil, err
+		}
+	}
+
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	refCommit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+	bases, err := headCommit.MergeBase(refCommit)
+	if err != nil {
+		return nil, err
+	}
+	if len(bases) == 0 {
+		return nil, fmt.Errorf("no common ancestor between %s and %s", branch, refBranch)
+	}
+
+	baseTree, err := bases[0].Tree()
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTree(baseTree, headTree)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both sides are kept, a removed or renamed file being owned as well
+	seen := map[string]bool{}
+	var files []string
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				files = append(files, name)
+			}
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// BitbucketRemote tells whether the origin remote is a Bitbucket Server one: an http clone through /scm/,
+// an ssh clone on the default 7999 port, or any clone from the host of the configured bitbucket server
+func BitbucketRemote(path, port, host, server string) bool {
+	if strings.Contains(path, "/scm/") || port == "7999" {
+		return true
+	}
+	if server == "" {
+		return false
+	}
+	u, err := url.Parse(server)
+	return err == nil && u.Hostname() == host
+}
+
+// BitbucketProject returns the Bitbucket Server project key of a clone namespace:
+// `scm/proj` (http clone, possibly under a context path) or `proj` (ssh clone) give `PROJ`,
+// personal repositories keeping their `~user` key
+func BitbucketProject(namespace string) string {
+	if i := strings.LastIndex(namespace, "scm/"); i >= 0 {
+		namespace = namespace[i+len("scm/"):]
+	}
+	if strings.HasPrefix(namespace, "~") {
+		return namespace
+	}
+	return strings.ToUpper(namespace)
+}
+
+// BitbucketBrowserURL returns the Bitbucket Server page of a repository, on the configured server
+// or, when none is set, on the clone host
+func BitbucketBrowserURL(server, host, project, name string) string {
+	base := strings.TrimSuffix(server, "/")
+	if base == "" {
+		base = "https://" + host
+	}
+	if strings.HasPrefix(project, "~") {
+		return base + "/users/" + project[1:] + "/repos/" + name
+	}
+	return base + "/projects/" + project + "/repos/" + name
+}
+
 // Local functions
 //
 //
@@ -689,6 +913,16 @@ func repoConfigAddSubSectParam(section, subsection, param, value string) {
 	repoCfg.Raw.Section(section).Subsection(subsection).AddOption(param, value)
 }
 
+func repoConfigSetSubSectParam(section, subsection, param, value string) {
+	// Create section and subsection if not present
+	if !repoCfg.Raw.HasSection(section) || !repoCfg.Raw.Section(section).HasSubsection(subsection) {
+		repoConfigAddSubSectParam(section, subsection, param, value)
+		return
+	}
+
+	repoCfg.Raw.Section(section).Subsection(subsection).SetOption(param, value)
+}
+
 func repoConfigInitWfSetup() error {
 
 	// Add section
@@ -869,6 +1103,54 @@ func repoBasePath() string {
 	return dir
 }
 
+// pull pulls origin into the worktree of r, returning the pull error as information
+func pull(r *git.Repository, pubKey *ssh.PublicKeys) string {
+	// Get the working directory for the repository
+	w, err := r.Worktree()
+	if err != nil {
+		if !Quiet {
+			SpinStopDisplay("fail")
+		}
+		log.Fatalln(err)
+	}
+
+	opts := &git.PullOptions{
+		RemoteName: originValue,
+		Force:      true,
+	}
+	if pubKey != nil {
+		opts.Auth = pubKey
+	}
+
+	var info string
+	// Pull the latest changes from the origin remote and merge into the current branch
+	log.Debugln("git pull origin")
+	err = w.Pull(opts)
+	if err != nil {
+		log.Debugln(err)
+		info = err.Error()
+	}
+
+	// Print the latest commit that was just pulled
+	ref, err := r.Head()
+	if err != nil {
+		if !Quiet {
+			SpinStopDisplay("fail")
+		}
+		log.Fatalln(err)
+	}
+	commit, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		if !Quiet {
+			SpinStopDisplay("fail")
+		}
+		log.Fatalln(err)
+	}
+
+	log.Debugln(commit)
+	return info
+}
+
 func fetchOrigin(refSpecStr string, pubKey *ssh.PublicKeys) error {
 	remote, err := repo.Remote(originValue)
 	if err != nil {
@@ -1073,7 +1355,7 @@ func readWorkingTreeFile(w *git.Worktree, filePath string) string {
 
 // diffOp represents a single diff operation
 type diffOp struct {
-	action byte   // '+', '-', or ' '
+	action byte // '+', '-', or ' '
 	line   string
 }
 
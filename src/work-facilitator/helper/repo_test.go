@@ -4,8 +4,13 @@ Copyright © 2024 Jean Bordat bordat.jean@gmail.com
 package helper
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/go-git/go-git/v5"
 )
 
 func TestFormatUnifiedDiff_NoChanges(t *testing.T) {
@@ -246,3 +251,160 @@ func TestMergeRegions_TooFar(t *testing.T) {
 		t.Errorf("Expected 2 separate regions, got %d", len(merged))
 	}
 }
+
+func TestBitbucketBrowserURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		server    string
+		namespace string
+		expected  string
+	}{
+		{
+			name:      "http clone",
+			server:    "https://bitbucket.example.com/",
+			namespace: "scm/proj",
+			expected:  "https://bitbucket.example.com/projects/PROJ/repos/repo",
+		},
+		{
+			name:      "http clone under a context path",
+			server:    "https://example.com/bitbucket",
+			namespace: "bitbucket/scm/proj",
+			expected:  "https://example.com/bitbucket/projects/PROJ/repos/repo",
+		},
+		{
+			name:      "ssh clone without server",
+			namespace: "proj",
+			expected:  "https://bitbucket.example.com/projects/PROJ/repos/repo",
+		},
+		{
+			name:      "personal repository",
+			server:    "https://bitbucket.example.com",
+			namespace: "~jdoe",
+			expected:  "https://bitbucket.example.com/users/jdoe/repos/repo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BitbucketBrowserURL(tt.server, "bitbucket.example.com", BitbucketProject(tt.namespace), "repo")
+			if got != tt.expected {
+				t.Errorf("BitbucketBrowserURL() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBitbucketRemote(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		port     string
+		host     string
+		server   string
+		expected bool
+	}{
+		{
+			name:     "http clone",
+			path:     "/scm/proj/repo.git",
+			host:     "git.example.com",
+			expected: true,
+		},
+		{
+			name:     "http clone under a context path",
+			path:     "/bitbucket/scm/proj/repo.git",
+			host:     "example.com",
+			expected: true,
+		},
+		{
+			name:     "ssh clone on the default port",
+			path:     "/proj/repo.git",
+			port:     "7999",
+			host:     "git.example.com",
+			expected: true,
+		},
+		{
+			name:     "ssh clone from the configured server",
+			path:     "/proj/repo.git",
+			host:     "bitbucket.example.com",
+			server:   "https://bitbucket.example.com/",
+			expected: true,
+		},
+		{
+			name:   "gitlab clone",
+			path:   "/group/repo.git",
+			host:   "gitlab.example.com",
+			server: "https://bitbucket.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BitbucketRemote(tt.path, tt.port, tt.host, tt.server); got != tt.expected {
+				t.Errorf("BitbucketRemote() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepoUnpushedCommits(t *testing.T) {
+	dir := newSyncRepo(t)
+	run := func(args ...string) {
+		if output, err := gitCommand(dir, args...); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	run("update-ref", "refs/remotes/origin/main", "main")
+
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldRepo := repo
+	repo = r
+	t.Cleanup(func() { repo = oldRepo })
+
+	tests := []struct {
+		name     string
+		setup    func()
+		expected []string
+	}{
+		{
+			name:     "never pushed stops at the reference branch fork",
+			setup:    func() {},
+			expected: []string{"feature change"},
+		},
+		{
+			name: "pushed stops at origin branch",
+			setup: func() {
+				run("update-ref", "refs/remotes/origin/feat/sync", "feat/sync")
+				if err := os.WriteFile(filepath.Join(dir, "app.txt"), []byte("second\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				run("commit", "-q", "-am", "second change")
+				run("commit", "-q", "--allow-empty", "-m", "third change")
+			},
+			expected: []string{"third change", "second change"},
+		},
+		{
+			name:  "up to date",
+			setup: func() { run("update-ref", "refs/remotes/origin/feat/sync", "feat/sync") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			commits, err := RepoUnpushedCommits("feat/sync", "main")
+			if err != nil {
+				t.Fatalf("RepoUnpushedCommits() unexpected error: %v", err)
+			}
+			var subjects []string
+			for _, commit := range commits {
+				subjects = append(subjects, commit.Subject)
+			}
+			if !reflect.DeepEqual(subjects, tt.expected) {
+				t.Errorf("RepoUnpushedCommits() = %v, want %v", subjects, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package helper
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	log "github.com/sirupsen/logrus"
+)
+
+// Rebases and merges stopping on conflicts are not handled by go-git, sync relies on the git command
+
+// RepoSyncInProgress returns the operation (rebase or merge) a previous sync left unfinished, empty when none
+func RepoSyncInProgress() string {
+	dir := repoBasePath()
+	// In a linked worktree, .git is a file pointing to the worktree own git directory
+	gitDir, err := gitCommand(dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return syncInProgress(filepath.Join(dir, ".git"))
+	}
+	return syncInProgress(strings.TrimSpace(gitDir))
+}
+
+// RepoSync rebases the current branch onto upstream, or merges upstream into it.
+// When it stops on conflicts, the conflicting files are returned.
+func RepoSync(strategy, upstream string) ([]string, error) {
+	args := []string{"rebase", upstream}
+	if strategy == c.SyncMerge {
+		args = []string{"merge", "--no-edit", upstream}
+	}
+	return syncRun(repoBasePath(), args...)
+}
+
+// RepoSyncContinue resumes the rebase or merge once the conflicts are resolved and staged.
+// The files still conflicting, or conflicting on the next rebased commit, are returned.
+func RepoSyncContinue(operation string) ([]string, error) {
+	dir := repoBasePath()
+	conflicts, err := unmergedFiles(dir)
+	if err != nil || len(conflicts) > 0 {
+		return conflicts, err
+	}
+	return syncRun(dir, operation, "--continue")
+}
+
+// RepoSyncAbort aborts the rebase or merge, restoring the branch as it was before the sync
+func RepoSyncAbort(operation string) error {
+	_, err := gitCommand(repoBasePath(), operation, "--abort")
+	return err
+}
+
+// RepoPushForceWithLease pushes the rewritten branch, as long as origin still has the branch as last fetched
+func RepoPushForceWithLease(pubKey *ssh.PublicKeys, branch string) error {
+	log.Debugln("git push --force-with-lease origin " + branch)
+
+	refSpec := config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", branch, branch))
+	opts := &git.PushOptions{
+		RemoteName:     originValue,
+		RefSpecs:       []config.RefSpec{refSpec},
+		ForceWithLease: &git.ForceWithLease{},
+	}
+	if pubKey != nil {
+		opts.Auth = pubKey
+	}
+
+	err := repo.Push(opts)
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	return nil
+}
+
+// syncInProgress tells which operation is unfinished in the git directory
+func syncInProgress(gitDir string) string {
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		if _, err := os.Stat(filepath.Join(gitDir, name)); err == nil {
+			return c.SyncRebase
+		}
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "MERGE_HEAD")); err == nil {
+		return c.SyncMerge
+	}
+	return ""
+}
+
+// syncRun runs a rebase or merge command, returning the conflicting files when it stops on conflicts
+func syncRun(dir string, args ...string) ([]string, error) {
+	output, err := gitCommand(dir, args...)
+	if err == nil {
+		return nil, nil
+	}
+
+	conflicts, errU := unmergedFiles(dir)
+	if errU == nil && len(conflicts) > 0 {
+		return conflicts, nil
+	}
+	return nil, fmt.Errorf("git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(output))
+}
+
+// unmergedFiles lists the files with unresolved conflicts
+func unmergedFiles(dir string) ([]string, error) {
+	output, err := gitCommand(dir, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, fmt.Errorf("conflicting files unknown: %s", strings.TrimSpace(output))
+	}
+
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// gitCommand runs git in dir, without opening an editor, and returns its combined output
+func gitCommand(dir string, args ...string) (string, error) {
+	log.Debugln("git " + strings.Join(args, " "))
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_EDITOR=true")
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
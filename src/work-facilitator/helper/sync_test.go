@@ -0,0 +1,135 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package helper
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"testing"
+)
+
+// newSyncRepo creates a repository where the feature branch and main both changed app.txt,
+// plus a file changed on main only
+func newSyncRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(args ...string) {
+		if output, err := gitCommand(dir, args...); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+
+	run("init", "-q", "-b", "main")
+	write("app.txt", "base\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "init")
+	run("checkout", "-q", "-b", "feat/sync")
+	write("app.txt", "feature\n")
+	run("commit", "-q", "-am", "feature change")
+	run("checkout", "-q", "main")
+	write("app.txt", "main\n")
+	write("other.txt", "main only\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "main change")
+	run("checkout", "-q", "feat/sync")
+
+	return dir
+}
+
+func TestSyncRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "rebase",
+			args:     []string{"rebase", "main"},
+			expected: c.SyncRebase,
+		},
+		{
+			name:     "merge",
+			args:     []string{"merge", "--no-edit", "main"},
+			expected: c.SyncMerge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newSyncRepo(t)
+			gitDir := filepath.Join(dir, ".git")
+
+			conflicts, err := syncRun(dir, tt.args...)
+			if err != nil {
+				t.Fatalf("syncRun() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(conflicts, []string{"app.txt"}) {
+				t.Errorf("conflicts = %v, want [app.txt]", conflicts)
+			}
+			if got := syncInProgress(gitDir); got != tt.expected {
+				t.Errorf("syncInProgress() = %v, want %v", got, tt.expected)
+			}
+
+			// Resolved and staged, the operation completes
+			if err := os.WriteFile(filepath.Join(dir, "app.txt"), []byte("main and feature\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if output, err := gitCommand(dir, "add", "app.txt"); err != nil {
+				t.Fatalf("git add: %v\n%s", err, output)
+			}
+			conflicts, err = syncRun(dir, tt.expected, "--continue")
+			if err != nil || len(conflicts) > 0 {
+				t.Fatalf("syncRun() --continue = %v, %v", conflicts, err)
+			}
+			if got := syncInProgress(gitDir); got != "" {
+				t.Errorf("syncInProgress() after continue = %v, want none", got)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "other.txt")); err != nil {
+				t.Errorf("main changes missing after sync: %v", err)
+			}
+		})
+	}
+}
+
+func TestSyncRun_Abort(t *testing.T) {
+	dir := newSyncRepo(t)
+
+	if _, err := syncRun(dir, "rebase", "main"); err != nil {
+		t.Fatalf("syncRun() unexpected error: %v", err)
+	}
+	if output, err := gitCommand(dir, c.SyncRebase, "--abort"); err != nil {
+		t.Fatalf("git rebase --abort: %v\n%s", err, output)
+	}
+	if got := syncInProgress(filepath.Join(dir, ".git")); got != "" {
+		t.Errorf("syncInProgress() after abort = %v, want none", got)
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, "app.txt"))
+	if string(content) != "feature\n" {
+		t.Errorf("app.txt after abort = %q, want the feature version", content)
+	}
+}
+
+func TestSyncRun_Error(t *testing.T) {
+	dir := newSyncRepo(t)
+
+	if _, err := syncRun(dir, "rebase", "origin/unknown"); err == nil {
+		t.Error("syncRun() expected error for an unknown upstream")
+	}
+}
@@ -0,0 +1,93 @@
+package helper
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	workStartParam = "work-start" // Start of the running work interval
+	workSinceParam = "work-since" // Start of the first interval not logged yet
+	workTimeParam  = "work-time"  // Seconds worked and not logged yet
+
+	workTimeLayout = time.RFC3339
+
+	RoundingUp      = "up"
+	RoundingDown    = "down"
+	RoundingNearest = "nearest"
+)
+
+// RepoConfigStartWork opens a work interval on the workflow, unless one is already running
+func RepoConfigStartWork(workflow string, now time.Time) {
+	if start, _ := RepoGetWorkflowParam(workflow, workStartParam); start != "" {
+		log.Debugln("Work interval already running since " + start)
+		return
+	}
+	repoConfigSetSubSectParam(wfSection, workflow, workStartParam, now.Format(workTimeLayout))
+	if since, _ := RepoGetWorkflowParam(workflow, workSinceParam); since == "" {
+		repoConfigSetSubSectParam(wfSection, workflow, workSinceParam, now.Format(workTimeLayout))
+	}
+}
+
+// RepoConfigStopWork closes the running work interval of the workflow.
+// It returns the time worked and not logged yet, and when that work started.
+func RepoConfigStopWork(workflow string, now time.Time) (time.Duration, time.Time) {
+	pending := repoGetWorkTime(workflow)
+
+	start, _ := RepoGetWorkflowParam(workflow, workStartParam)
+	if start != "" {
+		startTime, err := time.Parse(workTimeLayout, start)
+		if err != nil {
+			log.Warningln("Invalid work interval start '" + start + "', interval ignored")
+		} else if now.After(startTime) {
+			pending += now.Sub(startTime)
+		}
+		repoConfigSetSubSectParam(wfSection, workflow, workStartParam, "")
+		repoConfigSetSubSectParam(wfSection, workflow, workTimeParam, strconv.Itoa(int(pending.Seconds())))
+	}
+
+	since, _ := RepoGetWorkflowParam(workflow, workSinceParam)
+	sinceTime, err := time.Parse(workTimeLayout, since)
+	if err != nil {
+		sinceTime = now.Add(-pending)
+	}
+
+	return pending, sinceTime
+}
+
+// RepoConfigResetWork forgets the time worked on the workflow, once it has been logged
+func RepoConfigResetWork(workflow string) {
+	repoConfigSetSubSectParam(wfSection, workflow, workTimeParam, "0")
+	repoConfigSetSubSectParam(wfSection, workflow, workSinceParam, "")
+}
+
+// RoundDuration rounds a duration to a step of minutes, up, down or to the nearest step
+func RoundDuration(d time.Duration, stepMinutes int, rounding string) time.Duration {
+	if stepMinutes <= 0 || d <= 0 {
+		return d.Truncate(time.Minute)
+	}
+
+	step := time.Duration(stepMinutes) * time.Minute
+	steps := float64(d) / float64(step)
+	switch rounding {
+	case RoundingDown:
+		steps = math.Floor(steps)
+	case RoundingNearest:
+		steps = math.Round(steps)
+	default:
+		steps = math.Ceil(steps)
+	}
+
+	return time.Duration(steps) * step
+}
+
+func repoGetWorkTime(workflow string) time.Duration {
+	seconds, err := strconv.Atoi(repoGetWorkflowParam(workflow, workTimeParam))
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoundDuration(t *testing.T) {
+	tests := []struct {
+		name        string
+		duration    time.Duration
+		stepMinutes int
+		rounding    string
+		expected    time.Duration
+	}{
+		{
+			name:        "round up",
+			duration:    16 * time.Minute,
+			stepMinutes: 15,
+			rounding:    RoundingUp,
+			expected:    30 * time.Minute,
+		},
+		{
+			name:        "round down",
+			duration:    29 * time.Minute,
+			stepMinutes: 15,
+			rounding:    RoundingDown,
+			expected:    15 * time.Minute,
+		},
+		{
+			name:        "round nearest",
+			duration:    22*time.Minute + 30*time.Second,
+			stepMinutes: 15,
+			rounding:    RoundingNearest,
+			expected:    30 * time.Minute,
+		},
+		{
+			name:        "unknown rounding rounds up",
+			duration:    time.Minute,
+			stepMinutes: 15,
+			rounding:    "",
+			expected:    15 * time.Minute,
+		},
+		{
+			name:        "exact step is kept",
+			duration:    time.Hour,
+			stepMinutes: 15,
+			rounding:    RoundingUp,
+			expected:    time.Hour,
+		},
+		{
+			name:        "no step truncates to the minute",
+			duration:    10*time.Minute + 42*time.Second,
+			stepMinutes: 0,
+			rounding:    RoundingUp,
+			expected:    10 * time.Minute,
+		},
+		{
+			name:        "nothing worked",
+			duration:    0,
+			stepMinutes: 15,
+			rounding:    RoundingUp,
+			expected:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RoundDuration(tt.duration, tt.stepMinutes, tt.rounding)
+			if result != tt.expected {
+				t.Errorf("RoundDuration() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package helper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// Linked worktrees are not handled by go-git, the worktree mode relies on the git command
+
+// WorktreePath returns where the worktree of a workflow branch lives.
+// Without dir, the worktrees sit next to the main worktree in <main>.worktrees;
+// with dir (absolute, ~/ or relative to the main worktree), in <dir>/<repo name>.
+// The branch slashes are flattened, one directory per workflow.
+func WorktreePath(dir, mainPath, repoName, branch string) string {
+	root := mainPath + ".worktrees"
+	if dir != "" {
+		if strings.HasPrefix(dir, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				dir = filepath.Join(home, dir[2:])
+			}
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(mainPath, dir)
+		}
+		root = filepath.Join(dir, repoName)
+	}
+	return filepath.Join(root, strings.ReplaceAll(branch, "/", "-"))
+}
+
+// RepoWorktrees returns the path of the main worktree, the one holding the .git directory,
+// and the path of the worktree each branch is checked out in
+func RepoWorktrees() (string, map[string]string, error) {
+	return worktreeList(repoBasePath())
+}
+
+// RepoWorktreeAdd checks the branch out in a new worktree at path.
+// A missing branch is created from startPoint, without tracking it.
+func RepoWorktreeAdd(path, branch, startPoint string) error {
+	return worktreeAdd(repoBasePath(), path, branch, startPoint, branchExists(branch))
+}
+
+// RepoWorktreePull pulls origin in the worktree at path, as RepoPull does in the current one
+func RepoWorktreePull(path string, pubKey *ssh.PublicKeys) string {
+	r, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return err.Error()
+	}
+	return pull(r, pubKey)
+}
+
+// RepoWorktreeRemove deletes the worktree at path, refused by git when it has changes unless forced
+func RepoWorktreeRemove(path string, force bool) error {
+	return worktreeRemove(repoBasePath(), path, force)
+}
+
+// worktreeAdd runs git worktree add from dir
+func worktreeAdd(dir, path, branch, startPoint string, exists bool) error {
+	args := []string{"worktree", "add", path, branch}
+	if !exists {
+		args = []string{"worktree", "add", "--no-track", "-b", branch, path, startPoint}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	output, err := gitCommand(dir, args...)
+	if err != nil {
+		return fmt.Errorf("git worktree add failed: %s", strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// worktreeRemove runs git worktree remove from dir
+func worktreeRemove(dir, path string, force bool) error {
+	args := []string{"worktree", "remove", path}
+	if force {
+		args = []string{"worktree", "remove", "--force", path}
+	}
+	output, err := gitCommand(dir, args...)
+	if err != nil {
+		return fmt.Errorf("git worktree remove failed: %s", strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// worktreeList returns the main worktree path and the path of the worktree each branch is checked out in
+func worktreeList(dir string) (string, map[string]string, error) {
+	output, err := gitCommand(dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return "", nil, fmt.Errorf("git worktree list failed: %s", strings.TrimSpace(output))
+	}
+
+	mainPath := ""
+	path := ""
+	branches := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			path = strings.TrimPrefix(line, "worktree ")
+			if mainPath == "" {
+				mainPath = path
+			}
+		case strings.HasPrefix(line, "branch refs/heads/"):
+			branches[strings.TrimPrefix(line, "branch refs/heads/")] = path
+		}
+	}
+	return mainPath, branches, nil
+}
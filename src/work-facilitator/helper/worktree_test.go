@@ -0,0 +1,110 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorktreePath(t *testing.T) {
+	home, _ := os.UserHomeDir()
+
+	tests := []struct {
+		name     string
+		dir      string
+		branch   string
+		expected string
+	}{
+		{
+			name:     "default next to the main worktree",
+			branch:   "feat/add-login",
+			expected: "/src/app.worktrees/feat-add-login",
+		},
+		{
+			name:     "absolute dir",
+			dir:      "/work/trees",
+			branch:   "fix/crash",
+			expected: "/work/trees/app/fix-crash",
+		},
+		{
+			name:     "relative to the main worktree",
+			dir:      "../trees",
+			branch:   "fix/crash",
+			expected: "/src/trees/app/fix-crash",
+		},
+		{
+			name:     "home dir",
+			dir:      "~/trees",
+			branch:   "docs",
+			expected: filepath.Join(home, "trees", "app", "docs"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WorktreePath(tt.dir, "/src/app", "app", tt.branch); got != tt.expected {
+				t.Errorf("WorktreePath() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWorktreeAddRemove(t *testing.T) {
+	dir := newSyncRepo(t)
+	trees := t.TempDir()
+	created := filepath.Join(trees, "feat-new")
+	existing := filepath.Join(trees, "main")
+
+	// feat/sync is checked out in the main worktree, main is free
+	if err := worktreeAdd(dir, created, "feat/new", "main", false); err != nil {
+		t.Fatalf("worktreeAdd() new branch unexpected error: %v", err)
+	}
+	if err := worktreeAdd(dir, existing, "main", "", true); err != nil {
+		t.Fatalf("worktreeAdd() existing branch unexpected error: %v", err)
+	}
+	if err := worktreeAdd(dir, filepath.Join(trees, "again"), "main", "", true); err == nil {
+		t.Error("worktreeAdd() expected error for a branch checked out elsewhere")
+	}
+	if _, err := os.Stat(filepath.Join(created, "other.txt")); err != nil {
+		t.Errorf("new branch not started from main: %v", err)
+	}
+	if output, _ := gitCommand(dir, "config", "--get", "branch.feat/new.merge"); output != "" {
+		t.Errorf("new branch tracks %q, want no upstream", output)
+	}
+
+	mainPath, branches, err := worktreeList(created)
+	if err != nil {
+		t.Fatalf("worktreeList() unexpected error: %v", err)
+	}
+	resolved, _ := filepath.EvalSymlinks(dir)
+	if got, _ := filepath.EvalSymlinks(mainPath); got != resolved {
+		t.Errorf("main worktree = %v, want %v", mainPath, dir)
+	}
+	for branch, path := range map[string]string{"feat/sync": dir, "feat/new": created, "main": existing} {
+		got, _ := filepath.EvalSymlinks(branches[branch])
+		want, _ := filepath.EvalSymlinks(path)
+		if got != want {
+			t.Errorf("worktree of %v = %v, want %v", branch, branches[branch], path)
+		}
+	}
+
+	// A worktree with changes is only removed when forced
+	if err := os.WriteFile(filepath.Join(created, "wip.txt"), []byte("wip\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := worktreeRemove(dir, created, false); err == nil {
+		t.Error("worktreeRemove() expected error for a worktree with changes")
+	}
+	if err := worktreeRemove(dir, created, true); err != nil {
+		t.Fatalf("worktreeRemove() forced unexpected error: %v", err)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("worktree still present after removal: %v", err)
+	}
+	if _, branches, _ := worktreeList(dir); branches["feat/new"] != "" {
+		t.Errorf("removed worktree still listed: %v", branches["feat/new"])
+	}
+}
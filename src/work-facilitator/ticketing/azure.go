@@ -0,0 +1,218 @@
+package ticketing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultAzureServer hosts the Azure DevOps Services organizations
+	defaultAzureServer = "https://dev.azure.com"
+
+	// azureAPIVersion is the REST API version requested, supported by Azure DevOps Server 2022 as well
+	azureAPIVersion = "7.0"
+
+	// azureFields are the work item fields a ticket is built from
+	azureFields = "System.Title,System.WorkItemType,System.State,System.Tags"
+)
+
+// AzureTracker implements the Tracker interface for Azure DevOps Boards work items
+type AzureTracker struct {
+	apiURL string
+	token  string
+	states map[string]string
+	client *http.Client
+}
+
+func init() {
+	Register(c.AZURE, func(cfg c.Config, repo c.Repo, client *http.Client) (Tracker, error) {
+		return NewAzureTracker(c.AzureConfig{
+			Server:       cfg.TicketingAzureServer,
+			Organization: cfg.TicketingAzureOrganization,
+			Project:      cfg.TicketingAzureProject,
+			Token:        cfg.TicketingAzureToken,
+			States:       cfg.TicketingAzureStates,
+			HTTPClient:   client,
+		})
+	})
+}
+
+// NewAzureTracker creates a new Azure DevOps tracker for the organization project, authenticated with a PAT.
+// An empty server targets dev.azure.com, any other server is handled as Azure DevOps Server.
+func NewAzureTracker(cfg c.AzureConfig) (*AzureTracker, error) {
+	if cfg.Organization == "" || cfg.Project == "" {
+		return nil, errors.New("azure devops requires ticketing.azure.organization and ticketing.azure.project")
+	}
+	server := strings.TrimSuffix(cfg.Server, "/")
+	if server == "" {
+		server = defaultAzureServer
+	}
+
+	return &AzureTracker{
+		apiURL: server + "/" + url.PathEscape(cfg.Organization) + "/" + url.PathEscape(cfg.Project) + "/_apis/wit",
+		token:  cfg.Token,
+		states: cfg.States,
+		client: restClient(cfg.HTTPClient),
+	}, nil
+}
+
+// Name returns the tracker name
+func (t *AzureTracker) Name() string {
+	return c.AZURE
+}
+
+// ParseKey turns a work item id (`1234`, `#1234` or `AB#1234`) into a Ticket keyed by the bare id,
+// the `AB#` prefix of commits being left to the commit template
+func (t *AzureTracker) ParseKey(key string) (Ticket, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(strings.ToUpper(key), "AB"), "#"))
+	if err != nil {
+		return Ticket{Key: key}, fmt.Errorf("the issue should be an integer, corresponding to an Azure DevOps work item id")
+	}
+
+	return Ticket{Key: strconv.Itoa(id)}, nil
+}
+
+// FetchTicket retrieves an Azure DevOps work item, its tags becoming the ticket labels
+func (t *AzureTracker) FetchTicket(key string) (Ticket, error) {
+	ticket, err := t.ParseKey(key)
+	if err != nil {
+		return ticket, err
+	}
+
+	log.Debugf("key: %v\n", ticket.Key)
+
+	item, err := t.workItem(ticket.Key)
+	if err != nil {
+		return ticket, err
+	}
+	ticket.Title = item.Fields.Title
+	ticket.Type = item.Fields.WorkItemType
+	ticket.Status = item.Fields.State
+	for _, tag := range strings.Split(item.Fields.Tags, ";") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			ticket.Labels = append(ticket.Labels, tag)
+		}
+	}
+
+	return ticket, nil
+}
+
+// WorkflowContext builds the branch and commit prefix from the configured templates, `issue` being the work item id
+func (t *AzureTracker) WorkflowContext(cfg c.Config, ticket Ticket, branchType, commitType string) (string, string) {
+	branch := helper.Template(cfg.BranchTemplate, map[string]interface{}{
+		"type":    branchType,
+		"issue":   ticket.Key,
+		"summary": ticket.Title,
+	})
+	commit := helper.Template(cfg.CommitTemplate, map[string]interface{}{
+		"type":  commitType,
+		"issue": ticket.Key,
+	})
+
+	return branch, commit
+}
+
+// Refs returns the work item reference of a workflow
+func (t *AzureTracker) Refs(wf c.Workflow) []c.TicketRef {
+	return []c.TicketRef{
+		{Label: "work item", Param: helper.TICKETPARAM, Value: wf.Ticket},
+	}
+}
+
+// TransitionName returns the state configured for the lifecycle event
+func (t *AzureTracker) TransitionName(key, event string) string {
+	return t.states[event]
+}
+
+// Transition sets the state of the work item, nothing being sent when it is already in that state.
+// Workflows started with an `AB#1234` or `#1234` key are transitioned on the bare id.
+func (t *AzureTracker) Transition(key, name string) error {
+	ticket, err := t.ParseKey(key)
+	if err != nil {
+		return err
+	}
+	key = ticket.Key
+	item, err := t.workItem(key)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(item.Fields.State, name) {
+		log.Debugln("Azure work item " + key + " already in state " + item.Fields.State)
+		return nil
+	}
+
+	patch := []azurePatch{{Op: "add", Path: "/fields/System.State", Value: name}}
+	if err := t.send("PATCH", "/workitems/"+url.PathEscape(key)+"?api-version="+azureAPIVersion, patch, nil); err != nil {
+		return fmt.Errorf("azure state '%s' for work item %s (currently '%s') - %w", name, key, item.Fields.State, err)
+	}
+	return nil
+}
+
+// workItem retrieves the ticket fields of a work item, the key being given in any form ParseKey accepts
+func (t *AzureTracker) workItem(key string) (azureWorkItem, error) {
+	var item azureWorkItem
+	ticket, err := t.ParseKey(key)
+	if err != nil {
+		return item, err
+	}
+	path := "/workitems/" + url.PathEscape(ticket.Key) + "?fields=" + azureFields + "&api-version=" + azureAPIVersion
+	if err := t.send("GET", path, nil, &item); err != nil {
+		return item, fmt.Errorf("azure work item not found for key %s - %w", key, err)
+	}
+	return item, nil
+}
+
+// send calls the Azure DevOps REST API with an optional JSON patch and decodes the JSON response into v
+func (t *AzureTracker) send(method, path string, patch, v interface{}) error {
+	var payload io.Reader
+	if patch != nil {
+		content, err := json.Marshal(patch)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, t.apiURL+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if patch != nil {
+		req.Header.Set("Content-Type", "application/json-patch+json")
+	}
+	// Personal access tokens go in the password of a basic auth with an empty user
+	if t.token != "" {
+		req.SetBasicAuth("", t.token)
+	}
+
+	return doJSON(t.client, req, v)
+}
+
+// Azure DevOps API structures
+type azureWorkItem struct {
+	ID     int `json:"id"`
+	Fields struct {
+		Title        string `json:"System.Title"`
+		WorkItemType string `json:"System.WorkItemType"`
+		State        string `json:"System.State"`
+		Tags         string `json:"System.Tags"`
+	} `json:"fields"`
+}
+
+type azurePatch struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
@@ -0,0 +1,217 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package ticketing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"testing"
+)
+
+// newAzureServer starts an httptest stand-in of the Azure DevOps work item API,
+// recording the patches sent into patched
+func newAzureServer(t *testing.T, patched *[]map[string]interface{}) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/org/My%20Project/_apis/wit/workitems/1234", func(w http.ResponseWriter, r *http.Request) {
+		if user, token, _ := r.BasicAuth(); user != "" || token != "azure-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("api-version") != azureAPIVersion {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "api-version is required"})
+			return
+		}
+		if r.Method == http.MethodPatch {
+			if r.Header.Get("Content-Type") != "application/json-patch+json" {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			json.NewDecoder(r.Body).Decode(patched)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": 1234,
+			"fields": map[string]string{
+				"System.Title":        "Export fails for large reports",
+				"System.WorkItemType": "Bug",
+				"System.State":        "New",
+				"System.Tags":         "export; reports",
+			},
+		})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "TF401232: Work item does not exist"})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewAzureTracker(t *testing.T) {
+	if _, err := NewAzureTracker(c.AzureConfig{Organization: "org"}); err == nil {
+		t.Error("NewAzureTracker() expected error without project")
+	}
+
+	tracker, err := NewAzureTracker(c.AzureConfig{Organization: "org", Project: "My Project"})
+	if err != nil {
+		t.Fatalf("NewAzureTracker() unexpected error: %v", err)
+	}
+	if tracker.apiURL != "https://dev.azure.com/org/My%20Project/_apis/wit" {
+		t.Errorf("apiURL = %v, want https://dev.azure.com/org/My%%20Project/_apis/wit", tracker.apiURL)
+	}
+}
+
+func TestAzureTracker_ParseKey(t *testing.T) {
+	tracker := &AzureTracker{}
+
+	tests := []struct {
+		key      string
+		expected string
+		wantErr  bool
+	}{
+		{key: "1234", expected: "1234"},
+		{key: "#1234", expected: "1234"},
+		{key: "AB#1234", expected: "1234"},
+		{key: "ab#1234", expected: "1234"},
+		{key: "PROJ-1234", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			ticket, err := tracker.ParseKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && ticket.Key != tt.expected {
+				t.Errorf("Key = %v, want %v", ticket.Key, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAzureTracker_FetchTicket(t *testing.T) {
+	server := newAzureServer(t, nil)
+
+	tests := []struct {
+		name     string
+		token    string
+		key      string
+		expected Ticket
+		wantErr  bool
+	}{
+		{
+			name:  "work item",
+			token: "azure-test",
+			key:   "AB#1234",
+			expected: Ticket{
+				Key:    "1234",
+				Title:  "Export fails for large reports",
+				Type:   "Bug",
+				Status: "New",
+				Labels: []string{"export", "reports"},
+			},
+		},
+		{
+			name:    "missing token",
+			key:     "1234",
+			wantErr: true,
+		},
+		{
+			name:    "not found",
+			token:   "azure-test",
+			key:     "99",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, _ := NewAzureTracker(c.AzureConfig{Server: server.URL, Organization: "org", Project: "My Project", Token: tt.token})
+
+			ticket, err := tracker.FetchTicket(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchTicket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(ticket, tt.expected) {
+				t.Errorf("FetchTicket() = %+v, want %+v", ticket, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAzureTracker_WorkflowContext(t *testing.T) {
+	tracker := &AzureTracker{}
+	cfg := c.Config{
+		BranchTemplate: "{{type}}/{{issue}}_{{summary}}",
+		CommitTemplate: "{{type}}: AB#{{issue}} ",
+	}
+
+	branch, commit := tracker.WorkflowContext(cfg, Ticket{Key: "1234", Title: "export_fails"}, "fix", "fix")
+	if branch != "fix/1234_export_fails" {
+		t.Errorf("branch = %v, want fix/1234_export_fails", branch)
+	}
+	if commit != "fix: AB#1234 " {
+		t.Errorf("commit = %v, want 'fix: AB#1234 '", commit)
+	}
+}
+
+func TestAzureTracker_Transition(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		state    string
+		expected []map[string]interface{}
+	}{
+		{
+			name:  "state changed",
+			key:   "1234",
+			state: "Active",
+			expected: []map[string]interface{}{
+				{"op": "add", "path": "/fields/System.State", "value": "Active"},
+			},
+		},
+		{
+			name:  "prefixed key",
+			key:   "AB#1234",
+			state: "Active",
+			expected: []map[string]interface{}{
+				{"op": "add", "path": "/fields/System.State", "value": "Active"},
+			},
+		},
+		{
+			name:  "already in state",
+			key:   "#1234",
+			state: "new",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patched []map[string]interface{}
+			server := newAzureServer(t, &patched)
+			tracker, _ := NewAzureTracker(c.AzureConfig{
+				Server:       server.URL,
+				Organization: "org",
+				Project:      "My Project",
+				Token:        "azure-test",
+				States:       map[string]string{c.EventInit: tt.state},
+			})
+
+			name := tracker.TransitionName(tt.key, c.EventInit)
+			if err := tracker.Transition(tt.key, name); err != nil {
+				t.Fatalf("Transition() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(patched, tt.expected) {
+				t.Errorf("patch = %v, want %v", patched, tt.expected)
+			}
+		})
+	}
+}
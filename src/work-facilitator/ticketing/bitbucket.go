@@ -0,0 +1,259 @@
+package ticketing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// bitbucketStates maps the Bitbucket Server pull request states on the GitLab ones the commands check
+var bitbucketStates = map[string]string{
+	"OPEN":     "opened",
+	"MERGED":   "merged",
+	"DECLINED": "closed",
+}
+
+// BitbucketTracker implements the Tracker interface for Bitbucket Server / Data Center pull requests
+type BitbucketTracker struct {
+	apiURL  string
+	token   string
+	project string
+	repo    string
+	client  *http.Client
+}
+
+func init() {
+	Register(c.BITBUCKET, func(cfg c.Config, repo c.Repo, client *http.Client) (Tracker, error) {
+		return NewBitbucketTracker(c.BitbucketConfig{
+			Server:     cfg.TicketingBitbucketServer,
+			Token:      cfg.TicketingBitbucketToken,
+			HTTPClient: client,
+		}, repo.Namespace, repo.Name)
+	})
+}
+
+// NewBitbucketTracker creates a new Bitbucket Server tracker for the project/repo repository,
+// authenticated with an HTTP access token
+func NewBitbucketTracker(cfg c.BitbucketConfig, project, repo string) (*BitbucketTracker, error) {
+	if cfg.Server == "" {
+		return nil, errors.New("bitbucket server url is required")
+	}
+
+	return &BitbucketTracker{
+		apiURL:  strings.TrimSuffix(cfg.Server, "/") + "/rest/api/1.0",
+		token:   cfg.Token,
+		project: project,
+		repo:    repo,
+		client:  restClient(cfg.HTTPClient),
+	}, nil
+}
+
+// Name returns the tracker name
+func (t *BitbucketTracker) Name() string {
+	return c.BITBUCKET
+}
+
+// ParseKey turns a pull request id (`123` or `#123`) into a Ticket
+func (t *BitbucketTracker) ParseKey(key string) (Ticket, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(key, "#"))
+	if err != nil {
+		return Ticket{Key: key}, fmt.Errorf("the issue should be an integer, corresponding to a Bitbucket PR id")
+	}
+
+	return Ticket{Key: strconv.Itoa(id), ID: id}, nil
+}
+
+// FetchTicket retrieves a Bitbucket pull request, its source branch becoming the workflow branch
+func (t *BitbucketTracker) FetchTicket(key string) (Ticket, error) {
+	ticket, err := t.ParseKey(key)
+	if err != nil {
+		return ticket, err
+	}
+
+	log.Debugf("key: %v\n", ticket.ID)
+
+	pull, err := t.pullRequest(ticket.ID)
+	if err != nil {
+		return ticket, err
+	}
+	ticket.Title = pull.Title
+	ticket.Status = pull.State
+	ticket.Branch = pull.FromRef.DisplayID
+
+	return ticket, nil
+}
+
+// WorkflowContext uses the PR source branch (or the branch template) and a `type(#N): ` commit prefix
+func (t *BitbucketTracker) WorkflowContext(cfg c.Config, ticket Ticket, branchType, commitType string) (string, string) {
+	branch := ticket.Branch
+	if branch == "" {
+		branch = helper.Template(cfg.BranchTemplate, map[string]interface{}{
+			"type":    branchType,
+			"issue":   strconv.Itoa(ticket.ID),
+			"summary": ticket.Title,
+		})
+	}
+
+	return branch, fmt.Sprintf("%s(#%d): ", commitType, ticket.ID)
+}
+
+// Refs returns the pull request reference of a workflow
+func (t *BitbucketTracker) Refs(wf c.Workflow) []c.TicketRef {
+	return []c.TicketRef{
+		{Label: "pr", Param: helper.MRREFPARAM, Value: strconv.Itoa(wf.Issue)},
+	}
+}
+
+// CreateMergeRequest opens a pull request within the repository, unknown reviewers being skipped.
+// Bitbucket has no pull request labels, they are ignored.
+func (t *BitbucketTracker) CreateMergeRequest(mr MergeRequest) (Ticket, error) {
+	repository := bitbucketRepository{Slug: t.repo}
+	repository.Project.Key = t.project
+
+	create := bitbucketNewPull{
+		Title:       mr.Title,
+		Description: mr.Description,
+		Draft:       mr.Draft,
+		FromRef:     bitbucketRef{ID: "refs/heads/" + mr.SourceBranch, Repository: repository},
+		ToRef:       bitbucketRef{ID: "refs/heads/" + mr.TargetBranch, Repository: repository},
+	}
+	for _, username := range mr.Reviewers {
+		if err := t.get("/users/"+url.PathEscape(username), nil); err != nil {
+			log.Debugf("unknown reviewer %s skipped: %v\n", username, err)
+			continue
+		}
+		var reviewer bitbucketParticipant
+		reviewer.User.Name = username
+		create.Reviewers = append(create.Reviewers, reviewer)
+	}
+
+	var created bitbucketPull
+	if err := t.send("POST", t.repoPath()+"/pull-requests", create, &created); err != nil {
+		return Ticket{}, fmt.Errorf("bitbucket pr creation failed for branch %s - %w", mr.SourceBranch, err)
+	}
+	log.Debugf("pr created: #%v\n", created.ID)
+
+	return Ticket{
+		Key:    strconv.Itoa(created.ID),
+		ID:     created.ID,
+		Title:  created.Title,
+		Status: created.State,
+		Branch: created.FromRef.DisplayID,
+	}, nil
+}
+
+// MergeState returns the state of a Bitbucket pull request, with its merge commit once merged
+func (t *BitbucketTracker) MergeState(id int) (MergeState, error) {
+	pull, err := t.pullRequest(id)
+	if err != nil {
+		return MergeState{}, err
+	}
+
+	state, ok := bitbucketStates[pull.State]
+	if !ok {
+		state = strings.ToLower(pull.State)
+	}
+
+	return MergeState{
+		State:       state,
+		Merged:      pull.State == "MERGED",
+		MergeCommit: pull.Properties.MergeCommit.ID,
+	}, nil
+}
+
+// pullRequest retrieves a pull request of the repository
+func (t *BitbucketTracker) pullRequest(id int) (bitbucketPull, error) {
+	var pull bitbucketPull
+	if err := t.get(fmt.Sprintf("%s/pull-requests/%d", t.repoPath(), id), &pull); err != nil {
+		return pull, fmt.Errorf("bitbucket pr not found for key %d - %w", id, err)
+	}
+	return pull, nil
+}
+
+// repoPath returns the API path of the repository
+func (t *BitbucketTracker) repoPath() string {
+	return "/projects/" + url.PathEscape(t.project) + "/repos/" + url.PathEscape(t.repo)
+}
+
+// get calls the Bitbucket REST API and decodes the JSON response into v
+func (t *BitbucketTracker) get(path string, v interface{}) error {
+	return t.send("GET", path, nil, v)
+}
+
+// send calls the Bitbucket REST API with an optional JSON body and decodes the JSON response into v
+func (t *BitbucketTracker) send(method, path string, body, v interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, t.apiURL+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	return doJSON(t.client, req, v)
+}
+
+// Bitbucket API structures
+type bitbucketPull struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	FromRef struct {
+		DisplayID string `json:"displayId"`
+	} `json:"fromRef"`
+	Properties struct {
+		MergeCommit struct {
+			ID string `json:"id"`
+		} `json:"mergeCommit"`
+	} `json:"properties"`
+}
+
+type bitbucketNewPull struct {
+	Title       string                 `json:"title"`
+	Description string                 `json:"description,omitempty"`
+	Draft       bool                   `json:"draft,omitempty"`
+	FromRef     bitbucketRef           `json:"fromRef"`
+	ToRef       bitbucketRef           `json:"toRef"`
+	Reviewers   []bitbucketParticipant `json:"reviewers,omitempty"`
+}
+
+type bitbucketRef struct {
+	ID         string              `json:"id"`
+	Repository bitbucketRepository `json:"repository"`
+}
+
+type bitbucketRepository struct {
+	Slug    string `json:"slug"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+}
+
+type bitbucketParticipant struct {
+	User struct {
+		Name string `json:"name"`
+	} `json:"user"`
+}
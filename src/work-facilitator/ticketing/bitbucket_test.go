@@ -0,0 +1,224 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package ticketing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"testing"
+)
+
+// newBitbucketServer starts an httptest stand-in of the Bitbucket Server REST API,
+// recording the created pull request into created
+func newBitbucketServer(t *testing.T, created *map[string]interface{}) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") != "Bearer bitbucket-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"errors": []map[string]string{{"message": "Authentication failed"}},
+			})
+			return false
+		}
+		return true
+	}
+	mux.HandleFunc("/rest/api/1.0/projects/PROJ/repos/repo/pull-requests/12", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      12,
+			"title":   "Add SAML login",
+			"state":   "OPEN",
+			"fromRef": map[string]string{"id": "refs/heads/feat/saml-login", "displayId": "feat/saml-login"},
+		})
+	})
+	mux.HandleFunc("/rest/api/1.0/projects/PROJ/repos/repo/pull-requests/13", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":         13,
+			"title":      "Fix logout",
+			"state":      "MERGED",
+			"fromRef":    map[string]string{"displayId": "fix/logout"},
+			"properties": map[string]interface{}{"mergeCommit": map[string]string{"id": "a1b2c3d"}},
+		})
+	})
+	mux.HandleFunc("/rest/api/1.0/projects/PROJ/repos/repo/pull-requests", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !authorized(w, r) {
+			return
+		}
+		json.NewDecoder(r.Body).Decode(created)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      14,
+			"title":   (*created)["title"],
+			"state":   "OPEN",
+			"fromRef": map[string]string{"displayId": "feat/audit-log"},
+		})
+	})
+	mux.HandleFunc("/rest/api/1.0/users/alice", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "alice", "slug": "alice"})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []map[string]string{{"message": "not found"}},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewBitbucketTracker(t *testing.T) {
+	if _, err := NewBitbucketTracker(c.BitbucketConfig{}, "PROJ", "repo"); err == nil {
+		t.Error("NewBitbucketTracker() expected error without server")
+	}
+
+	tracker, err := NewBitbucketTracker(c.BitbucketConfig{Server: "https://bitbucket.example.com/"}, "PROJ", "repo")
+	if err != nil {
+		t.Fatalf("NewBitbucketTracker() unexpected error: %v", err)
+	}
+	if tracker.apiURL != "https://bitbucket.example.com/rest/api/1.0" {
+		t.Errorf("apiURL = %v, want https://bitbucket.example.com/rest/api/1.0", tracker.apiURL)
+	}
+}
+
+func TestBitbucketTracker_FetchTicket(t *testing.T) {
+	server := newBitbucketServer(t, nil)
+
+	tests := []struct {
+		name           string
+		token          string
+		key            string
+		expectedTitle  string
+		expectedBranch string
+		wantErr        bool
+	}{
+		{
+			name:           "pull request",
+			token:          "bitbucket-test",
+			key:            "#12",
+			expectedTitle:  "Add SAML login",
+			expectedBranch: "feat/saml-login",
+		},
+		{
+			name:    "missing token",
+			token:   "",
+			key:     "12",
+			wantErr: true,
+		},
+		{
+			name:    "not found",
+			token:   "bitbucket-test",
+			key:     "99",
+			wantErr: true,
+		},
+		{
+			name:    "invalid key",
+			token:   "bitbucket-test",
+			key:     "abc",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, _ := NewBitbucketTracker(c.BitbucketConfig{Server: server.URL, Token: tt.token}, "PROJ", "repo")
+
+			ticket, err := tracker.FetchTicket(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchTicket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if ticket.Title != tt.expectedTitle {
+				t.Errorf("Title = %v, want %v", ticket.Title, tt.expectedTitle)
+			}
+			if ticket.Branch != tt.expectedBranch {
+				t.Errorf("Branch = %v, want %v", ticket.Branch, tt.expectedBranch)
+			}
+		})
+	}
+}
+
+func TestBitbucketTracker_CreateMergeRequest(t *testing.T) {
+	var created map[string]interface{}
+	server := newBitbucketServer(t, &created)
+	tracker, _ := NewBitbucketTracker(c.BitbucketConfig{Server: server.URL, Token: "bitbucket-test"}, "PROJ", "repo")
+
+	ticket, err := tracker.CreateMergeRequest(MergeRequest{
+		Title:        "Audit log",
+		SourceBranch: "feat/audit-log",
+		TargetBranch: "main",
+		Labels:       []string{"feat"},
+		Reviewers:    []string{"alice", "ghost"},
+		Draft:        true,
+	})
+	if err != nil {
+		t.Fatalf("CreateMergeRequest() unexpected error: %v", err)
+	}
+	if ticket.ID != 14 || ticket.Branch != "feat/audit-log" {
+		t.Errorf("CreateMergeRequest() = %+v, want PR 14 on feat/audit-log", ticket)
+	}
+
+	expected := map[string]interface{}{
+		"title": "Audit log",
+		"draft": true,
+		"fromRef": map[string]interface{}{
+			"id":         "refs/heads/feat/audit-log",
+			"repository": map[string]interface{}{"slug": "repo", "project": map[string]interface{}{"key": "PROJ"}},
+		},
+		"toRef": map[string]interface{}{
+			"id":         "refs/heads/main",
+			"repository": map[string]interface{}{"slug": "repo", "project": map[string]interface{}{"key": "PROJ"}},
+		},
+		"reviewers": []interface{}{
+			map[string]interface{}{"user": map[string]interface{}{"name": "alice"}},
+		},
+	}
+	if !reflect.DeepEqual(created, expected) {
+		t.Errorf("created payload = %v, want %v", created, expected)
+	}
+}
+
+func TestBitbucketTracker_MergeState(t *testing.T) {
+	server := newBitbucketServer(t, nil)
+	tracker, _ := NewBitbucketTracker(c.BitbucketConfig{Server: server.URL, Token: "bitbucket-test"}, "PROJ", "repo")
+
+	tests := []struct {
+		name     string
+		id       int
+		expected MergeState
+	}{
+		{
+			name:     "open",
+			id:       12,
+			expected: MergeState{State: "opened"},
+		},
+		{
+			name:     "merged",
+			id:       13,
+			expected: MergeState{State: "merged", Merged: true, MergeCommit: "a1b2c3d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := tracker.MergeState(tt.id)
+			if err != nil {
+				t.Fatalf("MergeState() unexpected error: %v", err)
+			}
+			if state != tt.expected {
+				t.Errorf("MergeState() = %+v, want %+v", state, tt.expected)
+			}
+		})
+	}
+}
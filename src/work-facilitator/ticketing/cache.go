@@ -0,0 +1,107 @@
+package ticketing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Cache keeps the fetched tickets on disk, so repeated lookups are served locally
+// and lookups still work when the tracker is unreachable
+type Cache struct {
+	path    string
+	ttl     time.Duration
+	entries map[string]CacheEntry
+	now     func() time.Time
+}
+
+// CacheEntry is a cached ticket and the time it was fetched
+type CacheEntry struct {
+	Ticket  Ticket    `json:"ticket"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// StaleError is returned along with a cached ticket when the tracker could not be reached
+type StaleError struct {
+	Fetched time.Time
+	Err     error
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("%v - using the ticket cached on %s", e.Err, e.Fetched.Format(time.RFC1123))
+}
+
+func (e *StaleError) Unwrap() error {
+	return e.Err
+}
+
+// OpenCache loads the cache stored at path, an unreadable cache starting empty
+func OpenCache(path string, ttl time.Duration) *Cache {
+	cache := &Cache{path: path, ttl: ttl, entries: map[string]CacheEntry{}, now: time.Now}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debugln("ticket cache not read: " + err.Error())
+		}
+		return cache
+	}
+	if err := json.Unmarshal(content, &cache.entries); err != nil {
+		log.Debugln("ticket cache ignored: " + err.Error())
+		cache.entries = map[string]CacheEntry{}
+	}
+	return cache
+}
+
+// Fetch returns the cached ticket while it is fresh, unless refresh is set, and calls fetch otherwise.
+// When the tracker is unreachable (ErrUnreachable) and the ticket was cached, the cached ticket is returned
+// with a *StaleError. Rejected requests (not found, unauthorized...) fail as is.
+// A nil Cache always calls fetch.
+func (c *Cache) Fetch(key string, refresh bool, fetch func() (Ticket, error)) (Ticket, error) {
+	if c == nil {
+		return fetch()
+	}
+
+	entry, cached := c.entries[key]
+	if cached && !refresh && c.now().Sub(entry.Fetched) < c.ttl {
+		log.Debugf("ticket %s served from cache\n", key)
+		return entry.Ticket, nil
+	}
+
+	ticket, err := fetch()
+	if err != nil {
+		if cached && errors.Is(err, ErrUnreachable) {
+			return entry.Ticket, &StaleError{Fetched: entry.Fetched, Err: err}
+		}
+		return ticket, err
+	}
+
+	c.entries[key] = CacheEntry{Ticket: ticket, Fetched: c.now()}
+	if err := c.save(); err != nil {
+		log.Debugln("ticket cache not saved: " + err.Error())
+	}
+	return ticket, nil
+}
+
+// IsStale tells whether the error reports a fallback on a cached ticket
+func IsStale(err error) bool {
+	var stale *StaleError
+	return errors.As(err, &stale)
+}
+
+// save writes the cache on disk
+func (c *Cache) save() error {
+	content, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, content, 0600)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package ticketing
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache_Fetch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "tickets.json")
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	calls := 0
+	online := func() (Ticket, error) {
+		calls++
+		return Ticket{Key: "PROJ-1", Title: "Fix login"}, nil
+	}
+	offline := func() (Ticket, error) {
+		calls++
+		return Ticket{}, fmt.Errorf("jira %w - dial tcp: i/o timeout", ErrUnreachable)
+	}
+	rejected := func() (Ticket, error) {
+		calls++
+		return Ticket{}, errors.New("jira issue for key : PROJ-1 - request failed (HTTP 404)")
+	}
+
+	cache := OpenCache(path, time.Hour)
+	cache.now = func() time.Time { return now }
+
+	tests := []struct {
+		name          string
+		elapsed       time.Duration
+		refresh       bool
+		fetch         func() (Ticket, error)
+		expectedCalls int
+		expectedTitle string
+		stale         bool
+		wantErr       bool
+	}{
+		{
+			name:          "first lookup fetches",
+			fetch:         online,
+			expectedCalls: 1,
+			expectedTitle: "Fix login",
+		},
+		{
+			name:          "fresh entry served from cache",
+			elapsed:       30 * time.Minute,
+			fetch:         online,
+			expectedCalls: 1,
+			expectedTitle: "Fix login",
+		},
+		{
+			name:          "refresh bypasses the cache",
+			elapsed:       30 * time.Minute,
+			refresh:       true,
+			fetch:         online,
+			expectedCalls: 2,
+			expectedTitle: "Fix login",
+		},
+		{
+			name:          "expired entry falls back when offline",
+			elapsed:       3 * time.Hour,
+			fetch:         offline,
+			expectedCalls: 3,
+			expectedTitle: "Fix login",
+			stale:         true,
+		},
+		{
+			name:          "expired entry not served when rejected",
+			elapsed:       3 * time.Hour,
+			fetch:         rejected,
+			expectedCalls: 4,
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache.now = func() time.Time { return now.Add(tt.elapsed) }
+
+			ticket, err := cache.Fetch("JIRA:PROJ-1", tt.refresh, tt.fetch)
+			if tt.stale != IsStale(err) || (!tt.stale && (err != nil) != tt.wantErr) {
+				t.Fatalf("Fetch() error = %v, stale %v, wantErr %v", err, tt.stale, tt.wantErr)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("fetch calls = %v, want %v", calls, tt.expectedCalls)
+			}
+			if ticket.Title != tt.expectedTitle {
+				t.Errorf("Title = %v, want %v", ticket.Title, tt.expectedTitle)
+			}
+		})
+	}
+
+	// Entries survive a reload, unknown tickets still fail offline
+	reloaded := OpenCache(path, time.Hour)
+	reloaded.now = func() time.Time { return now }
+	if ticket, err := reloaded.Fetch("JIRA:PROJ-1", false, offline); err != nil || ticket.Title != "Fix login" {
+		t.Errorf("Fetch() after reload = %+v, %v, want cached ticket", ticket, err)
+	}
+	if _, err := reloaded.Fetch("JIRA:PROJ-2", false, offline); err == nil || IsStale(err) {
+		t.Errorf("Fetch() unknown ticket offline error = %v, want plain error", err)
+	}
+}
+
+func TestCache_FetchNil(t *testing.T) {
+	var cache *Cache
+
+	ticket, err := cache.Fetch("JIRA:PROJ-1", false, func() (Ticket, error) {
+		return Ticket{Key: "PROJ-1"}, nil
+	})
+	if err != nil || ticket.Key != "PROJ-1" {
+		t.Errorf("Fetch() = %+v, %v, want fetched ticket", ticket, err)
+	}
+}
@@ -0,0 +1,93 @@
+package ticketing
+
+import (
+	"net/http"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"strconv"
+)
+
+// CombinedTracker uses Jira for tickets and GitLab for the merge requests of the same branch.
+// Ticket operations (fetch, transitions, worklogs, comments) are the Jira ones.
+type CombinedTracker struct {
+	*JiraTracker
+	glab *GitlabTracker
+}
+
+func init() {
+	Register(c.JIRAGITLAB, func(cfg c.Config, repo c.Repo, client *http.Client) (Tracker, error) {
+		jira, err := NewJiraTracker(jiraConfig(cfg, client))
+		if err != nil {
+			return nil, err
+		}
+		glab, err := NewGitlabTracker(glabConfig(cfg, client), repo.FName)
+		if err != nil {
+			return nil, err
+		}
+		return NewCombinedTracker(jira, glab), nil
+	})
+}
+
+// NewCombinedTracker creates a tracker combining Jira tickets and GitLab merge requests
+func NewCombinedTracker(jira *JiraTracker, glab *GitlabTracker) *CombinedTracker {
+	return &CombinedTracker{JiraTracker: jira, glab: glab}
+}
+
+// Name returns the tracker name
+func (t *CombinedTracker) Name() string {
+	return c.JIRAGITLAB
+}
+
+// Refs returns the Jira ticket reference of a workflow, and its merge request once known
+func (t *CombinedTracker) Refs(wf c.Workflow) []c.TicketRef {
+	refs := t.JiraTracker.Refs(wf)
+	if wf.Issue != 0 {
+		refs = append(refs, c.TicketRef{Label: "mr", Param: helper.MRREFPARAM, Value: strconv.Itoa(wf.Issue)})
+	}
+	return refs
+}
+
+// FindMergeRequest looks up the open GitLab merge request of the branch
+func (t *CombinedTracker) FindMergeRequest(branch string) (Ticket, error) {
+	return t.glab.findMergeRequest(branch)
+}
+
+// CreateMergeRequest opens a GitLab merge request
+func (t *CombinedTracker) CreateMergeRequest(mr MergeRequest) (Ticket, error) {
+	return t.glab.CreateMergeRequest(mr)
+}
+
+// MergeState returns the state of a GitLab merge request
+func (t *CombinedTracker) MergeState(id int) (MergeState, error) {
+	return t.glab.MergeState(id)
+}
+
+// LatestPipeline returns the latest GitLab pipeline of the branch
+func (t *CombinedTracker) LatestPipeline(branch string) (c.Pipeline, error) {
+	return t.glab.LatestPipeline(branch)
+}
+
+// Review returns the review state of a GitLab merge request
+func (t *CombinedTracker) Review(id int) (c.Review, error) {
+	return t.glab.Review(id)
+}
+
+// MarkReady removes the draft flag of a GitLab merge request
+func (t *CombinedTracker) MarkReady(id int) error {
+	return t.glab.MarkReady(id)
+}
+
+// AssignReviewers requests a review of a GitLab merge request
+func (t *CombinedTracker) AssignReviewers(id int, usernames []string) ([]string, error) {
+	return t.glab.AssignReviewers(id, usernames)
+}
+
+// AddLabels adds labels to a GitLab merge request
+func (t *CombinedTracker) AddLabels(id int, labels []string) error {
+	return t.glab.AddLabels(id, labels)
+}
+
+// CommentMergeRequest posts a comment on a GitLab merge request
+func (t *CombinedTracker) CommentMergeRequest(id int, body string) error {
+	return t.glab.CommentMergeRequest(id, body)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package ticketing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"testing"
+)
+
+func TestNewCombinedTracker(t *testing.T) {
+	tracker, err := New(c.Config{Ticketing: c.JIRAGITLAB, TicketingJiraServer: "https://jira.example.com"}, c.Repo{FName: "group/app"}, nil)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if tracker.Name() != c.JIRAGITLAB {
+		t.Errorf("Name() = %v, want %v", tracker.Name(), c.JIRAGITLAB)
+	}
+
+	// Tickets are handled by Jira, merge requests by GitLab
+	if _, ok := tracker.(Transitioner); !ok {
+		t.Error("combined tracker should transition Jira tickets")
+	}
+	if _, ok := tracker.(MergeRequester); !ok {
+		t.Error("combined tracker should create GitLab merge requests")
+	}
+	if _, ok := tracker.(MergeRequestFinder); !ok {
+		t.Error("combined tracker should look up GitLab merge requests")
+	}
+	if _, ok := tracker.(IssueFetcher); ok {
+		t.Error("combined tracker should not fetch GitLab issues")
+	}
+}
+
+func TestCombinedTracker_WorkflowContextAndRefs(t *testing.T) {
+	jira, _ := NewJiraTracker(c.JiraConfig{})
+	glab, _ := NewGitlabTracker(c.GlabConfig{}, "42")
+	tracker := NewCombinedTracker(jira, glab)
+	cfg := c.Config{BranchTemplate: "{{type}}/{{issue}}_{{summary}}", CommitTemplate: "{{type}}({{issue}}): "}
+
+	// The merge request does not change the Jira based context
+	branch, commit := tracker.WorkflowContext(cfg, Ticket{Key: "PROJ-1", ID: 18, Title: "fix_login"}, "fix", "fix")
+	if branch != "fix/PROJ-1_fix_login" || commit != "fix(PROJ-1): " {
+		t.Errorf("WorkflowContext() = %v, %v", branch, commit)
+	}
+
+	refs := tracker.Refs(c.Workflow{Ticket: "PROJ-1"})
+	if len(refs) != 1 || refs[0].Param != "ticket" {
+		t.Errorf("Refs() without merge request = %+v", refs)
+	}
+	refs = tracker.Refs(c.Workflow{Ticket: "PROJ-1", Issue: 18})
+	if len(refs) != 2 || refs[1].Param != "mrref" || refs[1].Value != "18" {
+		t.Errorf("Refs() with merge request = %+v", refs)
+	}
+}
+
+func TestCombinedTracker_FindMergeRequest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/42/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		mrs := []map[string]interface{}{}
+		if r.URL.Query().Get("source_branch") == "fix/PROJ-1_fix_login" {
+			mrs = append(mrs, map[string]interface{}{"iid": 18, "title": "PROJ-1 fix login", "state": "opened", "source_branch": "fix/PROJ-1_fix_login"})
+		}
+		json.NewEncoder(w).Encode(mrs)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	jira, _ := NewJiraTracker(c.JiraConfig{})
+	glab, _ := NewGitlabTracker(c.GlabConfig{BaseUrl: server.URL}, "42")
+	tracker := NewCombinedTracker(jira, glab)
+
+	mr, err := tracker.FindMergeRequest("fix/PROJ-1_fix_login")
+	if err != nil {
+		t.Fatalf("FindMergeRequest() unexpected error: %v", err)
+	}
+	if mr.ID != 18 {
+		t.Errorf("FindMergeRequest() = %+v, want !18", mr)
+	}
+
+	mr, err = tracker.FindMergeRequest("feat/other")
+	if err != nil || mr.ID != 0 {
+		t.Errorf("FindMergeRequest() = %+v, %v, want no merge request", mr, err)
+	}
+}
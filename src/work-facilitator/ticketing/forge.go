@@ -0,0 +1,124 @@
+package ticketing
+
+import (
+	"fmt"
+	"net/http"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// forgeTracker serves the issues and pull requests of the GitHub-like REST APIs,
+// shared by GitHub and Gitea which only differ by their API root and authorization scheme
+type forgeTracker struct {
+	label      string
+	apiURL     string
+	accept     string
+	authScheme string
+	token      string
+	owner      string
+	repo       string
+	client     *http.Client
+}
+
+// ParseKey turns an issue or pull request number (`123` or `#123`) into a Ticket
+func (t *forgeTracker) ParseKey(key string) (Ticket, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(key, "#"))
+	if err != nil {
+		return Ticket{Key: key}, fmt.Errorf("the issue should be an integer, corresponding to a %s issue or PR number", t.label)
+	}
+
+	return Ticket{Key: strconv.Itoa(id), ID: id}, nil
+}
+
+// FetchTicket retrieves an issue, or the pull request it stands for
+func (t *forgeTracker) FetchTicket(key string) (Ticket, error) {
+	ticket, err := t.ParseKey(key)
+	if err != nil {
+		return ticket, err
+	}
+
+	log.Debugf("key: %v\n", ticket.ID)
+	name := strings.ToLower(t.label)
+
+	// Pull requests are issues as well, the issue endpoint tells us which one we got
+	var issue forgeIssue
+	if err := t.get(fmt.Sprintf("/repos/%s/%s/issues/%d", t.owner, t.repo, ticket.ID), &issue); err != nil {
+		return ticket, fmt.Errorf("%s issue not found for key %d - %w", name, ticket.ID, err)
+	}
+	ticket.Title = issue.Title
+	ticket.Status = issue.State
+	for _, label := range issue.Labels {
+		ticket.Labels = append(ticket.Labels, label.Name)
+	}
+
+	if issue.PullRequest != nil {
+		var pull forgePull
+		if err := t.get(fmt.Sprintf("/repos/%s/%s/pulls/%d", t.owner, t.repo, ticket.ID), &pull); err != nil {
+			return ticket, fmt.Errorf("%s pr not found for key %d - %w", name, ticket.ID, err)
+		}
+		ticket.Title = pull.Title
+		ticket.Branch = pull.Head.Ref
+	}
+
+	return ticket, nil
+}
+
+// WorkflowContext uses the PR head branch (or the branch template for issues) and a `type(#N): ` commit prefix
+func (t *forgeTracker) WorkflowContext(cfg c.Config, ticket Ticket, branchType, commitType string) (string, string) {
+	branch := ticket.Branch
+	if branch == "" {
+		branch = helper.Template(cfg.BranchTemplate, map[string]interface{}{
+			"type":    branchType,
+			"issue":   strconv.Itoa(ticket.ID),
+			"summary": ticket.Title,
+		})
+	}
+
+	return branch, fmt.Sprintf("%s(#%d): ", commitType, ticket.ID)
+}
+
+// Refs returns the issue or pull request reference of a workflow
+func (t *forgeTracker) Refs(wf c.Workflow) []c.TicketRef {
+	return []c.TicketRef{
+		{Label: "issue", Param: helper.MRREFPARAM, Value: strconv.Itoa(wf.Issue)},
+	}
+}
+
+// get calls the REST API and decodes the JSON response into v
+func (t *forgeTracker) get(path string, v interface{}) error {
+	req, err := http.NewRequest("GET", t.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", t.accept)
+	if t.token != "" {
+		req.Header.Set("Authorization", t.authScheme+" "+t.token)
+	}
+
+	return doJSON(t.client, req, v)
+}
+
+// GitHub and Gitea API response structures
+type forgeIssue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+type forgePull struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	Draft  bool   `json:"draft"`
+	Head   struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+}
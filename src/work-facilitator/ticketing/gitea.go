@@ -0,0 +1,46 @@
+package ticketing
+
+import (
+	"errors"
+	"net/http"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"strings"
+)
+
+// GiteaTracker implements the Tracker interface for Gitea and Forgejo issues and pull requests
+type GiteaTracker struct {
+	forgeTracker
+}
+
+func init() {
+	Register(c.GITEA, func(cfg c.Config, repo c.Repo, client *http.Client) (Tracker, error) {
+		return NewGiteaTracker(c.GiteaConfig{
+			Server:     cfg.TicketingGiteaServer,
+			Token:      cfg.TicketingGiteaToken,
+			HTTPClient: client,
+		}, repo.Namespace, repo.Name)
+	})
+}
+
+// NewGiteaTracker creates a new Gitea tracker for the owner/repo repository
+func NewGiteaTracker(cfg c.GiteaConfig, owner, repo string) (*GiteaTracker, error) {
+	if cfg.Server == "" {
+		return nil, errors.New("gitea server url is required")
+	}
+
+	return &GiteaTracker{forgeTracker{
+		label:      "Gitea",
+		apiURL:     strings.TrimSuffix(cfg.Server, "/") + "/api/v1",
+		accept:     "application/json",
+		authScheme: "token",
+		token:      cfg.Token,
+		owner:      owner,
+		repo:       repo,
+		client:     restClient(cfg.HTTPClient),
+	}}, nil
+}
+
+// Name returns the tracker name
+func (t *GiteaTracker) Name() string {
+	return c.GITEA
+}
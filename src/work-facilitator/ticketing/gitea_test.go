@@ -0,0 +1,70 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package ticketing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"testing"
+)
+
+// The issue and pull request logic is shared with GitHub and covered by github_test.go,
+// only the Gitea API root and authorization scheme are tested here
+
+func TestNewGiteaTracker(t *testing.T) {
+	if _, err := NewGiteaTracker(c.GiteaConfig{}, "owner", "repo"); err == nil {
+		t.Error("NewGiteaTracker() expected error without server")
+	}
+
+	tracker, err := NewGiteaTracker(c.GiteaConfig{Server: "https://gitea.example.com/"}, "owner", "repo")
+	if err != nil {
+		t.Fatalf("NewGiteaTracker() unexpected error: %v", err)
+	}
+	if tracker.apiURL != "https://gitea.example.com/api/v1" {
+		t.Errorf("apiURL = %v, want https://gitea.example.com/api/v1", tracker.apiURL)
+	}
+}
+
+func TestGiteaTracker_FetchTicket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/owner/repo/issues/7" || r.Header.Get("Authorization") != "token gitea-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"message": "token is required"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"number": 7, "title": "Broken pagination", "state": "open"})
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  "token scheme",
+			token: "gitea-test",
+		},
+		{
+			name:    "missing token",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, _ := NewGiteaTracker(c.GiteaConfig{Server: server.URL, Token: tt.token}, "owner", "repo")
+
+			ticket, err := tracker.FetchTicket("7")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchTicket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && ticket.Title != "Broken pagination" {
+				t.Errorf("Title = %v, want Broken pagination", ticket.Title)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package ticketing
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"strings"
+)
+
+var (
+	defaultGithubAPIURL = "https://api.github.com"
+)
+
+// GithubTracker implements the Tracker interface for GitHub issues and pull requests
+type GithubTracker struct {
+	forgeTracker
+}
+
+func init() {
+	Register(c.GITHUB, func(cfg c.Config, repo c.Repo, client *http.Client) (Tracker, error) {
+		return NewGithubTracker(c.GithubConfig{
+			Server:     cfg.TicketingGithubServer,
+			Token:      cfg.TicketingGithubToken,
+			HTTPClient: client,
+		}, repo.Namespace, repo.Name)
+	})
+}
+
+// NewGithubTracker creates a new GitHub tracker for the owner/repo repository.
+// An empty server targets github.com, any other server is handled as GitHub Enterprise.
+func NewGithubTracker(cfg c.GithubConfig, owner, repo string) (*GithubTracker, error) {
+	apiURL, err := githubAPIURL(cfg.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GithubTracker{forgeTracker{
+		label:      "GitHub",
+		apiURL:     apiURL,
+		accept:     "application/vnd.github+json",
+		authScheme: "Bearer",
+		token:      cfg.Token,
+		owner:      owner,
+		repo:       repo,
+		client:     restClient(cfg.HTTPClient),
+	}}, nil
+}
+
+// Name returns the tracker name
+func (t *GithubTracker) Name() string {
+	return c.GITHUB
+}
+
+// githubAPIURL returns the REST API root of github.com or of a GitHub Enterprise server
+func githubAPIURL(server string) (string, error) {
+	if server == "" {
+		return defaultGithubAPIURL, nil
+	}
+
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid github server url '%s'", server)
+	}
+	if u.Host == "github.com" || u.Host == "api.github.com" {
+		return defaultGithubAPIURL, nil
+	}
+
+	return strings.TrimSuffix(server, "/") + "/api/v3", nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package ticketing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"testing"
+)
+
+// newGithubServer starts an httptest stand-in of the GitHub Enterprise REST API
+func newGithubServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/owner/repo/issues/12", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ghp-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Bad credentials"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"number": 12,
+			"title":  "Login fails on Safari",
+			"state":  "open",
+			"labels": []map[string]string{{"name": "bug"}},
+		})
+	})
+	mux.HandleFunc("/api/v3/repos/owner/repo/issues/34", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"number":       34,
+			"title":        "Add dark mode",
+			"state":        "open",
+			"pull_request": map[string]string{"url": "https://ghe.example.com/api/v3/repos/owner/repo/pulls/34"},
+		})
+	})
+	mux.HandleFunc("/api/v3/repos/owner/repo/pulls/34", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"number": 34,
+			"title":  "Add dark mode",
+			"state":  "open",
+			"head":   map[string]string{"ref": "feat/dark-mode"},
+		})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Not Found"})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGithubAPIURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "empty server targets github.com",
+			server:   "",
+			expected: "https://api.github.com",
+		},
+		{
+			name:     "github.com",
+			server:   "https://github.com",
+			expected: "https://api.github.com",
+		},
+		{
+			name:     "enterprise server",
+			server:   "https://ghe.example.com/",
+			expected: "https://ghe.example.com/api/v3",
+		},
+		{
+			name:    "invalid server",
+			server:  "not a url",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := githubAPIURL(tt.server)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("githubAPIURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("githubAPIURL() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGithubTracker_ParseKey(t *testing.T) {
+	tracker, _ := NewGithubTracker(c.GithubConfig{}, "owner", "repo")
+
+	for _, key := range []string{"123", "#123"} {
+		ticket, err := tracker.ParseKey(key)
+		if err != nil {
+			t.Fatalf("ParseKey(%v) unexpected error: %v", key, err)
+		}
+		if ticket.ID != 123 || ticket.Key != "123" {
+			t.Errorf("ParseKey(%v) = %+v, want ID 123", key, ticket)
+		}
+	}
+
+	if _, err := tracker.ParseKey("PROJ-1"); err == nil {
+		t.Error("ParseKey() expected error for non numeric key")
+	}
+}
+
+func TestGithubTracker_FetchTicket(t *testing.T) {
+	server := newGithubServer(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	tests := []struct {
+		name           string
+		server         string
+		token          string
+		key            string
+		expectedTitle  string
+		expectedBranch string
+		wantErr        bool
+		unreachable    bool
+	}{
+		{
+			name:          "issue",
+			token:         "ghp-test",
+			key:           "12",
+			expectedTitle: "Login fails on Safari",
+		},
+		{
+			name:           "pull request",
+			token:          "ghp-test",
+			key:            "#34",
+			expectedTitle:  "Add dark mode",
+			expectedBranch: "feat/dark-mode",
+		},
+		{
+			name:    "bad credentials",
+			token:   "wrong",
+			key:     "12",
+			wantErr: true,
+		},
+		{
+			name:    "not found",
+			token:   "ghp-test",
+			key:     "99",
+			wantErr: true,
+		},
+		{
+			name:        "server down",
+			server:      down.URL,
+			token:       "ghp-test",
+			key:         "12",
+			wantErr:     true,
+			unreachable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := server.URL
+			if tt.server != "" {
+				url = tt.server
+			}
+			tracker, err := NewGithubTracker(c.GithubConfig{Server: url, Token: tt.token}, "owner", "repo")
+			if err != nil {
+				t.Fatalf("NewGithubTracker() unexpected error: %v", err)
+			}
+
+			ticket, err := tracker.FetchTicket(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchTicket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrUnreachable) != tt.unreachable {
+				t.Errorf("FetchTicket() error = %v, unreachable %v", err, tt.unreachable)
+			}
+			if tt.wantErr {
+				return
+			}
+			if ticket.Title != tt.expectedTitle {
+				t.Errorf("Title = %v, want %v", ticket.Title, tt.expectedTitle)
+			}
+			if ticket.Branch != tt.expectedBranch {
+				t.Errorf("Branch = %v, want %v", ticket.Branch, tt.expectedBranch)
+			}
+			if tt.expectedBranch == "" && (len(ticket.Labels) != 1 || ticket.Labels[0] != "bug") {
+				t.Errorf("Labels = %v, want [bug]", ticket.Labels)
+			}
+		})
+	}
+}
+
+func TestGithubTracker_WorkflowContext(t *testing.T) {
+	tracker, _ := NewGithubTracker(c.GithubConfig{}, "owner", "repo")
+	cfg := c.Config{BranchTemplate: "{{type}}/{{issue}}_{{summary}}"}
+
+	branch, commit := tracker.WorkflowContext(cfg, Ticket{Key: "123", ID: 123, Title: "fix_login"}, "fix", "fix")
+	if branch != "fix/123_fix_login" {
+		t.Errorf("branch = %v, want fix/123_fix_login", branch)
+	}
+	if commit != "fix(#123): " {
+		t.Errorf("commit = %v, want fix(#123): ", commit)
+	}
+
+	branch, _ = tracker.WorkflowContext(cfg, Ticket{Key: "34", ID: 34, Title: "dark_mode", Branch: "feat/dark-mode"}, "feat", "feat")
+	if branch != "feat/dark-mode" {
+		t.Errorf("branch = %v, want feat/dark-mode", branch)
+	}
+}
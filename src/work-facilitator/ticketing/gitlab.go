@@ -1,42 +1,528 @@
 package ticketing
 
 import (
+	"fmt"
+	"net/http"
+	"regexp"
 	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"spirit-dev/work-facilitator/work-facilitator/helper"
 	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/xanzy/go-gitlab"
 )
 
 var (
-	glabClient *gitlab.Client
+	// draftPrefix matches the title prefixes flagging a GitLab merge request as draft
+	draftPrefix = regexp.MustCompile(`(?i)^\s*(\[draft\]|\(draft\)|draft:|draft\s+-|wip:)\s*`)
 )
 
-func ClientGlab(cfg c.GlabConfig) {
-	gl, err := gitlab.NewClient(cfg.Token, gitlab.WithBaseURL(cfg.BaseUrl))
+// GitlabTracker implements the Tracker interface for GitLab merge requests
+type GitlabTracker struct {
+	client *gitlab.Client
+	pid    string
+}
+
+func init() {
+	Register(c.GITLAB, func(cfg c.Config, repo c.Repo, client *http.Client) (Tracker, error) {
+		return NewGitlabTracker(glabConfig(cfg, client), repo.FName)
+	})
+}
+
+// glabConfig extracts the GitLab settings from the workflow config
+func glabConfig(cfg c.Config, client *http.Client) c.GlabConfig {
+	return c.GlabConfig{
+		BaseUrl:    cfg.TicketingGlabServer,
+		Token:      cfg.TicketingGlabToken,
+		HTTPClient: client,
+	}
+}
+
+// NewGitlabTracker creates a new GitLab tracker for the given project path
+func NewGitlabTracker(cfg c.GlabConfig, pid string) (*GitlabTracker, error) {
+	options := []gitlab.ClientOptionFunc{gitlab.WithBaseURL(cfg.BaseUrl)}
+	if cfg.HTTPClient != nil {
+		options = append(options, gitlab.WithHTTPClient(cfg.HTTPClient))
+	}
+	gl, err := gitlab.NewClient(cfg.Token, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gitlab client: %w", err)
+	}
+
+	return &GitlabTracker{client: gl, pid: pid}, nil
+}
+
+// Name returns the tracker name
+func (t *GitlabTracker) Name() string {
+	return c.GITLAB
+}
+
+// ParseKey turns a merge request number into a Ticket
+func (t *GitlabTracker) ParseKey(key string) (Ticket, error) {
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		return Ticket{Key: key}, fmt.Errorf("the issue should be an integer, corresponding to a GitLab MR number")
+	}
+
+	return Ticket{Key: key, ID: id}, nil
+}
+
+// FetchTicket retrieves a GitLab merge request
+func (t *GitlabTracker) FetchTicket(key string) (Ticket, error) {
+	ticket, err := t.ParseKey(key)
+	if err != nil {
+		return ticket, err
+	}
+
+	log.Debugf("key: %v\n", ticket.ID)
+
+	pjt, resp, err := t.client.Projects.GetProject(t.pid, &gitlab.GetProjectOptions{})
+	if err != nil {
+		return ticket, fmt.Errorf("gitlab project not found for pid %s - %w", t.pid, unreachable(resp == nil, err))
+	}
+
+	mr, resp, err := t.client.MergeRequests.GetMergeRequest(pjt.ID, ticket.ID, &gitlab.GetMergeRequestsOptions{})
+	if err != nil {
+		return ticket, fmt.Errorf("gitlab mr not found for key %d - %w", ticket.ID, unreachable(resp == nil, err))
+	}
+
+	ticket.Title = helper.CleanGlabString(mr.Title)
+	ticket.Status = mr.State
+	ticket.Branch = mr.SourceBranch
+	ticket.Labels = mr.Labels
+	log.Debugf("mr.Title: `%v` --> `%v`\n", mr.Title, ticket.Title)
+
+	return ticket, nil
+}
+
+// FetchIssue retrieves a GitLab issue
+func (t *GitlabTracker) FetchIssue(key string) (Ticket, error) {
+	iid, err := strconv.Atoi(strings.TrimPrefix(key, "#"))
+	if err != nil {
+		return Ticket{Key: key}, fmt.Errorf("the issue should be an integer, corresponding to a GitLab issue number")
+	}
+	ticket := Ticket{Key: strconv.Itoa(iid), IssueID: iid}
+
+	log.Debugf("issue: %v\n", iid)
+
+	issue, resp, err := t.client.Issues.GetIssue(t.pid, iid)
+	if err != nil {
+		return ticket, fmt.Errorf("gitlab issue not found for key %d - %w", iid, unreachable(resp == nil, err))
+	}
+
+	ticket.Title = helper.CleanGlabString(issue.Title)
+	ticket.Status = issue.State
+	ticket.Labels = issue.Labels
+	log.Debugf("issue.Title: `%v` --> `%v`\n", issue.Title, ticket.Title)
+
+	return ticket, nil
+}
+
+// WorkflowContext uses the MR source branch (the branch template for issues, or the title)
+// and a `type(!N): ` commit prefix, `type(#N): ` for an issue without merge request
+func (t *GitlabTracker) WorkflowContext(cfg c.Config, ticket Ticket, branchType, commitType string) (string, string) {
+	branch := ticket.Branch
+	if branch == "" && ticket.IssueID != 0 {
+		branch = helper.Template(cfg.BranchTemplate, map[string]interface{}{
+			"type":    branchType,
+			"issue":   strconv.Itoa(ticket.IssueID),
+			"summary": ticket.Title,
+		})
+	}
+	if branch == "" {
+		branch = ticket.Title
+	}
+
+	if ticket.ID == 0 && ticket.IssueID != 0 {
+		return branch, fmt.Sprintf("%s(#%d): ", commitType, ticket.IssueID)
+	}
+	return branch, fmt.Sprintf("%s(!%d): ", commitType, ticket.ID)
+}
+
+// Refs returns the merge request reference of a workflow, and the issue it resolves if any
+func (t *GitlabTracker) Refs(wf c.Workflow) []c.TicketRef {
+	if wf.LinkedIssue == 0 {
+		return []c.TicketRef{
+			{Label: "issue", Param: helper.MRREFPARAM, Value: strconv.Itoa(wf.Issue)},
+		}
+	}
+
+	refs := []c.TicketRef{
+		{Label: "issue", Param: helper.ISSUEREFPARAM, Value: strconv.Itoa(wf.LinkedIssue)},
+	}
+	// The merge request of an issue workflow is only known once created or found
+	if wf.Issue != 0 {
+		refs = append(refs, c.TicketRef{Label: "mr", Param: helper.MRREFPARAM, Value: strconv.Itoa(wf.Issue)})
+	}
+	return refs
+}
+
+// CreateMergeRequest opens a merge request assigned to the token owner, unknown reviewers being skipped
+func (t *GitlabTracker) CreateMergeRequest(mr MergeRequest) (Ticket, error) {
+	user, _, err := t.client.Users.CurrentUser()
+	if err != nil {
+		return Ticket{}, fmt.Errorf("gitlab current user not found - %w", err)
+	}
+
+	title := mr.Title
+	if mr.Draft {
+		title = "Draft: " + title
+	}
+	labels := gitlab.LabelOptions(mr.Labels)
+	reviewers, unknown, err := t.userIDs(mr.Reviewers)
+	if err != nil {
+		return Ticket{}, err
+	}
+	if len(unknown) > 0 {
+		log.Debugf("unknown reviewers skipped: %v\n", unknown)
+	}
+
+	opts := &gitlab.CreateMergeRequestOptions{
+		Title:        &title,
+		Description:  &mr.Description,
+		SourceBranch: &mr.SourceBranch,
+		TargetBranch: &mr.TargetBranch,
+		AssigneeID:   &user.ID,
+		Labels:       &labels,
+	}
+	if len(reviewers) > 0 {
+		opts.ReviewerIDs = &reviewers
+	}
+
+	created, _, err := t.client.MergeRequests.CreateMergeRequest(t.pid, opts)
+	if err != nil {
+		return Ticket{}, fmt.Errorf("gitlab mr creation failed for branch %s - %w", mr.SourceBranch, err)
+	}
+	log.Debugf("mr created: !%v %v\n", created.IID, created.WebURL)
+
+	return Ticket{
+		Key:    strconv.Itoa(created.IID),
+		ID:     created.IID,
+		Title:  helper.CleanGlabString(created.Title),
+		Status: created.State,
+		Branch: created.SourceBranch,
+	}, nil
+}
+
+// CreateTicket creates a GitLab issue assigned to the token owner, the ticket type being ignored
+func (t *GitlabTracker) CreateTicket(nt NewTicket) (Ticket, error) {
+	user, _, err := t.client.Users.CurrentUser()
+	if err != nil {
+		return Ticket{}, fmt.Errorf("gitlab current user not found - %w", err)
+	}
+
+	created, _, err := t.client.Issues.CreateIssue(t.pid, &gitlab.CreateIssueOptions{
+		Title:       &nt.Summary,
+		Description: &nt.Description,
+		AssigneeIDs: &[]int{user.ID},
+	})
+	if err != nil {
+		return Ticket{}, fmt.Errorf("gitlab issue creation failed for pid %s - %w", t.pid, err)
+	}
+	log.Debugf("issue created: #%v %v\n", created.IID, created.WebURL)
+
+	return Ticket{
+		Key:     strconv.Itoa(created.IID),
+		IssueID: created.IID,
+		Title:   created.Title,
+		Status:  created.State,
+	}, nil
+}
+
+// MergeState returns the state of a GitLab merge request, with its merge (or squash) commit once merged
+func (t *GitlabTracker) MergeState(id int) (MergeState, error) {
+	mr, _, err := t.client.MergeRequests.GetMergeRequest(t.pid, id, &gitlab.GetMergeRequestsOptions{})
 	if err != nil {
-		helper.SpinStopDisplay("fail")
-		log.Fatalf("Failed to create client: %v", err)
+		return MergeState{}, fmt.Errorf("gitlab mr not found for key %d - %w", id, err)
 	}
-	glabClient = gl
+
+	state := MergeState{State: mr.State, Merged: mr.State == "merged"}
+	if state.Merged {
+		state.MergeCommit = mr.MergeCommitSHA
+		if state.MergeCommit == "" {
+			state.MergeCommit = mr.SquashCommitSHA
+		}
+	}
+
+	return state, nil
 }
 
-func GetGlabIssue(key int, pid string) *gitlab.MergeRequest {
+// MarkReady removes the draft prefix from the merge request title, GitLab deriving the draft flag from it.
+// A draft without prefix, set through the API or the UI, is marked ready with the /ready quick action.
+func (t *GitlabTracker) MarkReady(id int) error {
+	mr, _, err := t.client.MergeRequests.GetMergeRequest(t.pid, id, &gitlab.GetMergeRequestsOptions{})
+	if err != nil {
+		return fmt.Errorf("gitlab mr not found for key %d - %w", id, err)
+	}
+
+	title := undraftTitle(mr.Title)
+	if title != mr.Title {
+		if _, _, err := t.client.MergeRequests.UpdateMergeRequest(t.pid, id, &gitlab.UpdateMergeRequestOptions{Title: &title}); err != nil {
+			return fmt.Errorf("gitlab mr update failed for key %d - %w", id, err)
+		}
+		return nil
+	}
+	if !mr.Draft {
+		log.Debugf("mr !%d is not a draft\n", id)
+		return nil
+	}
 
-	log.Debugf("key: %v\n", key)
+	if err := t.CommentMergeRequest(id, "/ready"); err != nil {
+		return err
+	}
+	mr, _, err = t.client.MergeRequests.GetMergeRequest(t.pid, id, &gitlab.GetMergeRequestsOptions{})
+	if err != nil {
+		return fmt.Errorf("gitlab mr not found for key %d - %w", id, err)
+	}
+	if mr.Draft {
+		return fmt.Errorf("gitlab mr !%d is a draft without title prefix and could not be marked ready", id)
+	}
+	return nil
+}
 
-	pjt, _, err := glabClient.Projects.GetProject(pid, &gitlab.GetProjectOptions{})
+// AssignReviewers adds the users to the merge request reviewers, keeping the current ones
+func (t *GitlabTracker) AssignReviewers(id int, usernames []string) ([]string, error) {
+	mr, _, err := t.client.MergeRequests.GetMergeRequest(t.pid, id, &gitlab.GetMergeRequestsOptions{})
 	if err != nil {
-		helper.SpinStopDisplay("fail")
-		log.Fatalln("Gitlab project not found for pid " + pid + " - " + err.Error())
+		return nil, fmt.Errorf("gitlab mr not found for key %d - %w", id, err)
 	}
 
-	mr, _, err := glabClient.MergeRequests.GetMergeRequest(pjt.ID, key, &gitlab.GetMergeRequestsOptions{})
+	ids, unknown, err := t.userIDs(usernames)
 	if err != nil {
-		helper.SpinStopDisplay("fail")
-		log.Fatalln("Gitlab mr not found for key " + strconv.Itoa(key) + " - " + err.Error())
+		return nil, err
+	}
+	var reviewers []int
+	for _, reviewer := range mr.Reviewers {
+		reviewers = append(reviewers, reviewer.ID)
+	}
+	reviewers = append(reviewers, ids...)
+
+	if _, _, err := t.client.MergeRequests.UpdateMergeRequest(t.pid, id, &gitlab.UpdateMergeRequestOptions{ReviewerIDs: &reviewers}); err != nil {
+		return unknown, fmt.Errorf("gitlab mr update failed for key %d - %w", id, err)
+	}
+	return unknown, nil
+}
+
+// AddLabels adds the labels to the merge request, keeping the current ones
+func (t *GitlabTracker) AddLabels(id int, labels []string) error {
+	add := gitlab.LabelOptions(labels)
+	if _, _, err := t.client.MergeRequests.UpdateMergeRequest(t.pid, id, &gitlab.UpdateMergeRequestOptions{AddLabels: &add}); err != nil {
+		return fmt.Errorf("gitlab mr update failed for key %d - %w", id, err)
+	}
+	return nil
+}
+
+// CommentMergeRequest posts a note on the merge request
+func (t *GitlabTracker) CommentMergeRequest(id int, body string) error {
+	if _, _, err := t.client.Notes.CreateMergeRequestNote(t.pid, id, &gitlab.CreateMergeRequestNoteOptions{Body: &body}); err != nil {
+		return fmt.Errorf("gitlab mr comment failed for key %d - %w", id, err)
+	}
+	return nil
+}
+
+// userIDs resolves usernames into user ids, returning the usernames that could not be resolved
+func (t *GitlabTracker) userIDs(usernames []string) ([]int, []string, error) {
+	var ids []int
+	var unknown []string
+	for _, username := range usernames {
+		username := username
+		users, _, err := t.client.Users.ListUsers(&gitlab.ListUsersOptions{Username: &username})
+		if err != nil {
+			return nil, nil, fmt.Errorf("gitlab user lookup failed for %s - %w", username, err)
+		}
+		if len(users) == 0 {
+			unknown = append(unknown, username)
+			continue
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids, unknown, nil
+}
+
+// undraftTitle strips the draft prefixes GitLab recognizes from a merge request title
+func undraftTitle(title string) string {
+	return draftPrefix.ReplaceAllString(title, "")
+}
+
+// Review returns the draft flag, approvals, unresolved discussions and merge status of a GitLab merge request
+func (t *GitlabTracker) Review(id int) (c.Review, error) {
+	mr, _, err := t.client.MergeRequests.GetMergeRequest(t.pid, id, &gitlab.GetMergeRequestsOptions{})
+	if err != nil {
+		return c.Review{}, fmt.Errorf("gitlab mr not found for key %d - %w", id, err)
+	}
+
+	review := c.Review{
+		ID:           mr.IID,
+		Draft:        mr.Draft,
+		HasConflicts: mr.HasConflicts,
+		Mergeable:    mr.DetailedMergeStatus == "mergeable",
+		MergeStatus:  mr.DetailedMergeStatus,
+		WebURL:       mr.WebURL,
+	}
+
+	approvals, _, err := t.client.MergeRequestApprovals.GetConfiguration(t.pid, id)
+	if err != nil {
+		return review, fmt.Errorf("gitlab mr approvals not found for key %d - %w", id, err)
+	}
+	review.Approvals = len(approvals.ApprovedBy)
+	review.ApprovalsRequired = approvals.ApprovalsRequired
+
+	opts := &gitlab.ListMergeRequestDiscussionsOptions{PerPage: 100}
+	for {
+		discussions, resp, err := t.client.Discussions.ListMergeRequestDiscussions(t.pid, id, opts)
+		if err != nil {
+			return review, fmt.Errorf("gitlab mr discussions not found for key %d - %w", id, err)
+		}
+		for _, discussion := range discussions {
+			// A thread is resolved through its notes, the first one tells whether it can be
+			if len(discussion.Notes) > 0 && discussion.Notes[0].Resolvable && !discussion.Notes[0].Resolved {
+				review.UnresolvedDiscussions++
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return review, nil
+}
+
+// LatestPipeline returns the latest pipeline of the branch, with its stages and failed jobs
+func (t *GitlabTracker) LatestPipeline(branch string) (c.Pipeline, error) {
+	orderBy := "id"
+	desc := "desc"
+	pipelines, _, err := t.client.Pipelines.ListProjectPipelines(t.pid, &gitlab.ListProjectPipelinesOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 1},
+		Ref:         &branch,
+		OrderBy:     &orderBy,
+		Sort:        &desc,
+	})
+	if err != nil {
+		return c.Pipeline{}, fmt.Errorf("gitlab pipeline list failed for branch %s - %w", branch, err)
+	}
+	if len(pipelines) == 0 {
+		return c.Pipeline{}, nil
+	}
+
+	pipeline := c.Pipeline{
+		ID:     pipelines[0].ID,
+		Status: pipelines[0].Status,
+		WebURL: pipelines[0].WebURL,
+	}
+
+	jobs, _, err := t.client.Jobs.ListPipelineJobs(t.pid, pipeline.ID, &gitlab.ListJobsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return pipeline, fmt.Errorf("gitlab job list failed for pipeline %d - %w", pipeline.ID, err)
+	}
+
+	// Jobs come newest first, the earliest job of a stage gives the stage order
+	var stages []string
+	statuses := map[string][]string{}
+	for i := len(jobs) - 1; i >= 0; i-- {
+		job := jobs[i]
+		if _, ok := statuses[job.Stage]; !ok {
+			stages = append(stages, job.Stage)
+		}
+		status := job.Status
+		if status == "failed" && job.AllowFailure {
+			status = "success"
+		} else if status == "failed" {
+			pipeline.FailedJobs = append(pipeline.FailedJobs, c.PipelineJob{Name: job.Name, Stage: job.Stage, WebURL: job.WebURL})
+		}
+		statuses[job.Stage] = append(statuses[job.Stage], status)
+	}
+	for _, stage := range stages {
+		pipeline.Stages = append(pipeline.Stages, c.PipelineStage{Name: stage, Status: stageStatus(statuses[stage])})
+	}
+
+	return pipeline, nil
+}
+
+// stageStatus aggregates the job statuses of a stage, the most significant one winning
+func stageStatus(statuses []string) string {
+	for _, status := range []string{"failed", "running", "pending", "created", "canceled", "manual", "success"} {
+		for _, s := range statuses {
+			if s == status {
+				return status
+			}
+		}
+	}
+	if len(statuses) > 0 {
+		return statuses[0]
+	}
+	return ""
+}
+
+// ListAssigned returns the open merge requests and issues of the project assigned to the token owner
+func (t *GitlabTracker) ListAssigned() ([]Ticket, error) {
+	user, _, err := t.client.Users.CurrentUser()
+	if err != nil {
+		return nil, fmt.Errorf("gitlab current user not found - %w", err)
+	}
+	opened := "opened"
+
+	mrs, _, err := t.client.MergeRequests.ListProjectMergeRequests(t.pid, &gitlab.ListProjectMergeRequestsOptions{
+		State:      &opened,
+		AssigneeID: gitlab.AssigneeID(user.ID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gitlab mr list failed for pid %s - %w", t.pid, err)
+	}
+	issues, _, err := t.client.Issues.ListProjectIssues(t.pid, &gitlab.ListProjectIssuesOptions{
+		State:      &opened,
+		AssigneeID: gitlab.AssigneeID(user.ID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gitlab issue list failed for pid %s - %w", t.pid, err)
+	}
+
+	var tickets []Ticket
+	for _, mr := range mrs {
+		tickets = append(tickets, Ticket{
+			Key:    strconv.Itoa(mr.IID),
+			ID:     mr.IID,
+			Title:  helper.CleanGlabString(mr.Title),
+			Status: mr.State,
+			Branch: mr.SourceBranch,
+			Labels: mr.Labels,
+		})
+	}
+	for _, issue := range issues {
+		tickets = append(tickets, Ticket{
+			Key:     strconv.Itoa(issue.IID),
+			IssueID: issue.IID,
+			Title:   helper.CleanGlabString(issue.Title),
+			Status:  issue.State,
+			Labels:  issue.Labels,
+		})
+	}
+
+	return tickets, nil
+}
+
+// findMergeRequest returns the open merge request of the source branch, a zero Ticket when there is none
+func (t *GitlabTracker) findMergeRequest(branch string) (Ticket, error) {
+	opened := "opened"
+	mrs, _, err := t.client.MergeRequests.ListProjectMergeRequests(t.pid, &gitlab.ListProjectMergeRequestsOptions{
+		State:        &opened,
+		SourceBranch: &branch,
+	})
+	if err != nil {
+		return Ticket{}, fmt.Errorf("gitlab mr list failed for branch %s - %w", branch, err)
+	}
+	if len(mrs) == 0 {
+		return Ticket{}, nil
 	}
 
-	return mr
+	return Ticket{
+		Key:    strconv.Itoa(mrs[0].IID),
+		ID:     mrs[0].IID,
+		Title:  helper.CleanGlabString(mrs[0].Title),
+		Status: mrs[0].State,
+		Branch: mrs[0].SourceBranch,
+	}, nil
 }
@@ -0,0 +1,503 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package ticketing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"testing"
+)
+
+// newGitlabServer starts an httptest stand-in of the GitLab REST API for project 42.
+// The payload of created issues, of created or updated merge requests, and of notes is recorded in created.
+func newGitlabServer(t *testing.T, created *map[string]interface{}) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/user", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 7, "username": "me"})
+	})
+	mux.HandleFunc("/api/v4/projects/42/issues", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			json.NewDecoder(r.Body).Decode(created)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":    1006,
+				"iid":   6,
+				"title": (*created)["title"],
+				"state": "opened",
+			})
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"id": 1005, "iid": 5, "title": "Login fails on Safari", "state": "opened"},
+		})
+	})
+	mux.HandleFunc("/api/v4/projects/42/issues/5", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    1005,
+			"iid":   5,
+			"title": "Login fails on Safari",
+			"state": "opened",
+		})
+	})
+	mux.HandleFunc("/api/v4/projects/42/issues/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "404 Issue Not Found"})
+	})
+	mux.HandleFunc("/api/v4/projects/42/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"iid": 18, "title": `Draft: Resolve "Add dark mode"`, "state": "opened", "source_branch": "feat/dark-mode"},
+			})
+			return
+		}
+		json.NewDecoder(r.Body).Decode(created)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"iid":           18,
+			"title":         (*created)["title"],
+			"state":         "opened",
+			"source_branch": (*created)["source_branch"],
+		})
+	})
+
+	mux.HandleFunc("/api/v4/projects/42/merge_requests/18", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"iid":               18,
+			"state":             "merged",
+			"squash_commit_sha": "a1b2c3d",
+		})
+	})
+	mux.HandleFunc("/api/v4/projects/42/merge_requests/19", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			json.NewDecoder(r.Body).Decode(created)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"iid":                   19,
+			"title":                 "Draft: Add dark mode",
+			"reviewers":             []map[string]interface{}{{"id": 3}},
+			"state":                 "opened",
+			"draft":                 true,
+			"has_conflicts":         true,
+			"detailed_merge_status": "broken_status",
+		})
+	})
+	mux.HandleFunc("/api/v4/projects/42/merge_requests/19/notes", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(created)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 1})
+	})
+	// Drafts without title prefix: the /ready quick action clears the flag of 21, not of 22
+	drafts := map[int]bool{21: true, 22: true}
+	for _, iid := range []int{21, 22} {
+		iid := iid
+		mux.HandleFunc(fmt.Sprintf("/api/v4/projects/42/merge_requests/%d", iid), func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"iid": iid, "title": "Add light mode", "state": "opened", "draft": drafts[iid]})
+		})
+		mux.HandleFunc(fmt.Sprintf("/api/v4/projects/42/merge_requests/%d/notes", iid), func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(created)
+			if (*created)["body"] == "/ready" && iid == 21 {
+				drafts[iid] = false
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 2})
+		})
+	}
+	mux.HandleFunc("/api/v4/users", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("username") != "alice" {
+			json.NewEncoder(w).Encode([]map[string]interface{}{})
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"id": 11, "username": "alice"}})
+	})
+	mux.HandleFunc("/api/v4/projects/42/merge_requests/19/approvals", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"approvals_required": 2,
+			"approved_by":        []map[string]interface{}{{"user": map[string]interface{}{"id": 3}}},
+		})
+	})
+	mux.HandleFunc("/api/v4/projects/42/merge_requests/19/discussions", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"id": "a", "notes": []map[string]interface{}{{"id": 1, "resolvable": true, "resolved": false}}},
+			{"id": "b", "notes": []map[string]interface{}{{"id": 2, "resolvable": true, "resolved": true}}},
+			{"id": "c", "notes": []map[string]interface{}{{"id": 3, "resolvable": false}}},
+		})
+	})
+	mux.HandleFunc("/api/v4/projects/42/pipelines", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ref") != "feat/dark-mode" {
+			json.NewEncoder(w).Encode([]map[string]interface{}{})
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"id": 77, "status": "failed", "ref": "feat/dark-mode", "web_url": "https://gitlab.example.com/pipelines/77"},
+		})
+	})
+	mux.HandleFunc("/api/v4/projects/42/pipelines/77/jobs", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"id": 4, "name": "deploy", "stage": "deploy", "status": "skipped"},
+			{"id": 3, "name": "lint", "stage": "test", "status": "failed", "allow_failure": true},
+			{"id": 2, "name": "unit", "stage": "test", "status": "failed", "web_url": "https://gitlab.example.com/jobs/2"},
+			{"id": 1, "name": "compile", "stage": "build", "status": "success"},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGitlabTracker_CreateMergeRequest(t *testing.T) {
+	created := map[string]interface{}{}
+	server := newGitlabServer(t, &created)
+	tracker, err := NewGitlabTracker(c.GlabConfig{BaseUrl: server.URL, Token: "glpat-test"}, "42")
+	if err != nil {
+		t.Fatalf("NewGitlabTracker() unexpected error: %v", err)
+	}
+
+	ticket, err := tracker.CreateMergeRequest(MergeRequest{
+		Title:        "fix_login",
+		SourceBranch: "fix_login",
+		TargetBranch: "main",
+		Labels:       []string{"fix"},
+		Reviewers:    []string{"alice", "ghost"},
+		Draft:        true,
+	})
+	if err != nil {
+		t.Fatalf("CreateMergeRequest() unexpected error: %v", err)
+	}
+
+	if ticket.ID != 18 || ticket.Key != "18" || ticket.Branch != "fix_login" {
+		t.Errorf("CreateMergeRequest() = %+v, want !18 on fix_login", ticket)
+	}
+	if created["title"] != "Draft: fix_login" {
+		t.Errorf("title = %v, want Draft: fix_login", created["title"])
+	}
+	if created["target_branch"] != "main" {
+		t.Errorf("target_branch = %v, want main", created["target_branch"])
+	}
+	if created["assignee_id"] != float64(7) {
+		t.Errorf("assignee_id = %v, want 7", created["assignee_id"])
+	}
+	if created["labels"] != "fix" {
+		t.Errorf("labels = %v, want fix", created["labels"])
+	}
+	if !reflect.DeepEqual(created["reviewer_ids"], []interface{}{float64(11)}) {
+		t.Errorf("reviewer_ids = %v, want [11]", created["reviewer_ids"])
+	}
+}
+
+func TestGitlabTracker_FetchIssue(t *testing.T) {
+	server := newGitlabServer(t, &map[string]interface{}{})
+	tracker, _ := NewGitlabTracker(c.GlabConfig{BaseUrl: server.URL, Token: "glpat-test"}, "42")
+
+	tests := []struct {
+		name          string
+		key           string
+		expectedTitle string
+		wantErr       bool
+	}{
+		{
+			name:          "issue",
+			key:           "5",
+			expectedTitle: "Login fails on Safari",
+		},
+		{
+			name:          "issue with hash",
+			key:           "#5",
+			expectedTitle: "Login fails on Safari",
+		},
+		{
+			name:    "not found",
+			key:     "6",
+			wantErr: true,
+		},
+		{
+			name:    "invalid key",
+			key:     "PROJ-5",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticket, err := tracker.FetchIssue(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchIssue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if ticket.IssueID != 5 || ticket.ID != 0 {
+				t.Errorf("FetchIssue() = %+v, want issue 5 without merge request", ticket)
+			}
+			if ticket.Title != tt.expectedTitle {
+				t.Errorf("Title = %v, want %v", ticket.Title, tt.expectedTitle)
+			}
+		})
+	}
+}
+
+func TestGitlabTracker_IssueWorkflow(t *testing.T) {
+	tracker, _ := NewGitlabTracker(c.GlabConfig{}, "42")
+	cfg := c.Config{BranchTemplate: "{{type}}/{{issue}}_{{summary}}"}
+
+	branch, commit := tracker.WorkflowContext(cfg, Ticket{Key: "5", IssueID: 5, Title: "fix_login"}, "fix", "fix")
+	if branch != "fix/5_fix_login" {
+		t.Errorf("branch = %v, want fix/5_fix_login", branch)
+	}
+	if commit != "fix(#5): " {
+		t.Errorf("commit = %v, want fix(#5): ", commit)
+	}
+
+	_, commit = tracker.WorkflowContext(cfg, Ticket{Key: "18", ID: 18, IssueID: 5, Branch: "fix/5_fix_login"}, "fix", "fix")
+	if commit != "fix(!18): " {
+		t.Errorf("commit = %v, want fix(!18): ", commit)
+	}
+
+	refs := tracker.Refs(c.Workflow{Issue: 18, LinkedIssue: 5})
+	if len(refs) != 2 || refs[0].Param != "issueref" || refs[0].Value != "5" || refs[1].Param != "mrref" || refs[1].Value != "18" {
+		t.Errorf("Refs() = %+v", refs)
+	}
+	// An issue workflow has no merge request yet
+	refs = tracker.Refs(c.Workflow{LinkedIssue: 5})
+	if len(refs) != 1 || refs[0].Param != "issueref" || refs[0].Value != "5" {
+		t.Errorf("Refs() = %+v, want the issue only", refs)
+	}
+}
+
+func TestGitlabTracker_MergeState(t *testing.T) {
+	server := newGitlabServer(t, &map[string]interface{}{})
+	tracker, _ := NewGitlabTracker(c.GlabConfig{BaseUrl: server.URL, Token: "glpat-test"}, "42")
+
+	tests := []struct {
+		name     string
+		id       int
+		expected MergeState
+		wantErr  bool
+	}{
+		{
+			name:     "merged with squash commit",
+			id:       18,
+			expected: MergeState{State: "merged", Merged: true, MergeCommit: "a1b2c3d"},
+		},
+		{
+			name:     "still open",
+			id:       19,
+			expected: MergeState{State: "opened"},
+		},
+		{
+			name:    "not found",
+			id:      20,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := tracker.MergeState(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MergeState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if state != tt.expected {
+				t.Errorf("MergeState() = %+v, want %+v", state, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGitlabTracker_ListAssigned(t *testing.T) {
+	server := newGitlabServer(t, &map[string]interface{}{})
+	tracker, _ := NewGitlabTracker(c.GlabConfig{BaseUrl: server.URL, Token: "glpat-test"}, "42")
+
+	tickets, err := tracker.ListAssigned()
+	if err != nil {
+		t.Fatalf("ListAssigned() unexpected error: %v", err)
+	}
+	if len(tickets) != 2 {
+		t.Fatalf("ListAssigned() = %+v, want one merge request and one issue", tickets)
+	}
+	if tickets[0].ID != 18 || tickets[0].Branch != "feat/dark-mode" || tickets[0].Title != "Add dark mode" {
+		t.Errorf("merge request = %+v", tickets[0])
+	}
+	if tickets[1].IssueID != 5 || tickets[1].ID != 0 {
+		t.Errorf("issue = %+v", tickets[1])
+	}
+}
+
+func TestGitlabTracker_LatestPipeline(t *testing.T) {
+	server := newGitlabServer(t, &map[string]interface{}{})
+	tracker, _ := NewGitlabTracker(c.GlabConfig{BaseUrl: server.URL, Token: "glpat-test"}, "42")
+
+	pipeline, err := tracker.LatestPipeline("feat/dark-mode")
+	if err != nil {
+		t.Fatalf("LatestPipeline() unexpected error: %v", err)
+	}
+	if pipeline.ID != 77 || pipeline.Status != "failed" || !pipeline.Finished() {
+		t.Errorf("LatestPipeline() = %+v, want finished failed pipeline 77", pipeline)
+	}
+
+	expectedStages := []c.PipelineStage{{Name: "build", Status: "success"}, {Name: "test", Status: "failed"}, {Name: "deploy", Status: "skipped"}}
+	if !reflect.DeepEqual(pipeline.Stages, expectedStages) {
+		t.Errorf("Stages = %+v, want %+v", pipeline.Stages, expectedStages)
+	}
+	if len(pipeline.FailedJobs) != 1 || pipeline.FailedJobs[0].Name != "unit" {
+		t.Errorf("FailedJobs = %+v, want [unit]", pipeline.FailedJobs)
+	}
+
+	pipeline, err = tracker.LatestPipeline("feat/no-ci")
+	if err != nil || pipeline.ID != 0 {
+		t.Errorf("LatestPipeline() = %+v, %v, want zero pipeline", pipeline, err)
+	}
+}
+
+func TestStageStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		expected string
+	}{
+		{name: "all passed", statuses: []string{"success", "success"}, expected: "success"},
+		{name: "one failed", statuses: []string{"success", "failed", "running"}, expected: "failed"},
+		{name: "still running", statuses: []string{"success", "running", "pending"}, expected: "running"},
+		{name: "skipped", statuses: []string{"skipped"}, expected: "skipped"},
+		{name: "empty", statuses: nil, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := stageStatus(tt.statuses); result != tt.expected {
+				t.Errorf("stageStatus() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGitlabTracker_Review(t *testing.T) {
+	server := newGitlabServer(t, &map[string]interface{}{})
+	tracker, _ := NewGitlabTracker(c.GlabConfig{BaseUrl: server.URL, Token: "glpat-test"}, "42")
+
+	review, err := tracker.Review(19)
+	if err != nil {
+		t.Fatalf("Review() unexpected error: %v", err)
+	}
+
+	expected := c.Review{
+		ID:                    19,
+		Draft:                 true,
+		Approvals:             1,
+		ApprovalsRequired:     2,
+		UnresolvedDiscussions: 1,
+		HasConflicts:          true,
+		MergeStatus:           "broken_status",
+	}
+	if review != expected {
+		t.Errorf("Review() = %+v, want %+v", review, expected)
+	}
+
+	if _, err := tracker.Review(20); err == nil {
+		t.Error("Review() expected error for unknown merge request")
+	}
+}
+
+func TestUndraftTitle(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected string
+	}{
+		{title: "Draft: Add dark mode", expected: "Add dark mode"},
+		{title: "[Draft] Add dark mode", expected: "Add dark mode"},
+		{title: "(draft) Add dark mode", expected: "Add dark mode"},
+		{title: "Draft - Add dark mode", expected: "Add dark mode"},
+		{title: "WIP: Add dark mode", expected: "Add dark mode"},
+		{title: "Add draft mode", expected: "Add draft mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if result := undraftTitle(tt.title); result != tt.expected {
+				t.Errorf("undraftTitle() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGitlabTracker_Ready(t *testing.T) {
+	var updated map[string]interface{}
+	server := newGitlabServer(t, &updated)
+	tracker, _ := NewGitlabTracker(c.GlabConfig{BaseUrl: server.URL, Token: "glpat-test"}, "42")
+
+	if err := tracker.MarkReady(19); err != nil {
+		t.Fatalf("MarkReady() unexpected error: %v", err)
+	}
+	if updated["title"] != "Add dark mode" {
+		t.Errorf("title = %v, want Add dark mode", updated["title"])
+	}
+
+	unknown, err := tracker.AssignReviewers(19, []string{"alice", "ghost"})
+	if err != nil {
+		t.Fatalf("AssignReviewers() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(unknown, []string{"ghost"}) {
+		t.Errorf("unknown = %v, want [ghost]", unknown)
+	}
+	if !reflect.DeepEqual(updated["reviewer_ids"], []interface{}{float64(3), float64(11)}) {
+		t.Errorf("reviewer_ids = %v, want [3 11]", updated["reviewer_ids"])
+	}
+
+	if err := tracker.AddLabels(19, []string{"review"}); err != nil {
+		t.Fatalf("AddLabels() unexpected error: %v", err)
+	}
+	if updated["add_labels"] != "review" {
+		t.Errorf("add_labels = %v, want review", updated["add_labels"])
+	}
+
+	if err := tracker.CommentMergeRequest(19, "Ready for review"); err != nil {
+		t.Fatalf("CommentMergeRequest() unexpected error: %v", err)
+	}
+	if updated["body"] != "Ready for review" {
+		t.Errorf("body = %v, want Ready for review", updated["body"])
+	}
+
+	if err := tracker.MarkReady(20); err == nil {
+		t.Error("MarkReady() expected error for unknown merge request")
+	}
+
+	// A draft without title prefix is marked ready with the /ready quick action
+	updated = nil
+	if err := tracker.MarkReady(21); err != nil {
+		t.Fatalf("MarkReady() draft without prefix unexpected error: %v", err)
+	}
+	if updated["body"] != "/ready" {
+		t.Errorf("body = %v, want /ready", updated["body"])
+	}
+	if err := tracker.MarkReady(22); err == nil {
+		t.Error("MarkReady() expected error for a merge request still draft")
+	}
+}
+
+func TestGitlabTracker_CreateTicket(t *testing.T) {
+	created := map[string]interface{}{}
+	server := newGitlabServer(t, &created)
+	tracker, _ := NewGitlabTracker(c.GlabConfig{BaseUrl: server.URL, Token: "glpat-test"}, "42")
+
+	ticket, err := tracker.CreateTicket(NewTicket{Summary: "Login fails on Firefox", Description: "Steps to reproduce"})
+	if err != nil {
+		t.Fatalf("CreateTicket() unexpected error: %v", err)
+	}
+
+	if ticket.Key != "6" || ticket.IssueID != 6 || ticket.ID != 0 || ticket.Title != "Login fails on Firefox" {
+		t.Errorf("CreateTicket() = %+v, want issue #6", ticket)
+	}
+	if created["description"] != "Steps to reproduce" {
+		t.Errorf("description = %v, want Steps to reproduce", created["description"])
+	}
+	if !reflect.DeepEqual(created["assignee_ids"], []interface{}{float64(7)}) {
+		t.Errorf("assignee_ids = %v, want [7]", created["assignee_ids"])
+	}
+}
@@ -1,35 +1,313 @@
 package ticketing
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	c "spirit-dev/work-facilitator/work-facilitator/common"
 	"spirit-dev/work-facilitator/work-facilitator/helper"
+	"strings"
+	"time"
 
 	jira "github.com/andygrunwald/go-jira"
 	log "github.com/sirupsen/logrus"
 )
 
-var (
-	jiraClient *jira.Client
+const (
+	// defaultTransitions is the transitions entry used for projects without their own
+	defaultTransitions = "default"
+
+	// defaultPickJQL lists the open issues assigned to the user
+	defaultPickJQL = "assignee = currentUser() AND statusCategory != Done ORDER BY updated DESC"
+
+	// defaultTicketType is the type of the issues created without an explicit type
+	defaultTicketType = "Task"
 )
 
-func ClientJira(cfg c.JiraConfig) {
+// JiraTracker implements the Tracker interface for Jira
+type JiraTracker struct {
+	client      *jira.Client
+	auth        string
+	pickJQL     string
+	transitions map[string]map[string]string
+}
+
+func init() {
+	Register(c.JIRA, func(cfg c.Config, repo c.Repo, client *http.Client) (Tracker, error) {
+		return NewJiraTracker(jiraConfig(cfg, client))
+	})
+}
+
+// jiraConfig extracts the Jira settings from the workflow config
+func jiraConfig(cfg c.Config, client *http.Client) c.JiraConfig {
+	return c.JiraConfig{
+		Server:      cfg.TicketingJiraServer,
+		Auth:        cfg.TicketingJiraAuth,
+		Username:    cfg.TicketingJiraUsername,
+		Password:    cfg.TicketingJiraPassword,
+		Token:       cfg.TicketingJiraToken,
+		PickJQL:     cfg.TicketingJiraPickJQL,
+		Transitions: cfg.TicketingJiraTransitions,
+		HTTPClient:  client,
+	}
+}
+
+// NewJiraTracker creates a new Jira tracker, authenticated with the configured method
+func NewJiraTracker(cfg c.JiraConfig) (*JiraTracker, error) {
+	httpClient, err := jiraHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := jira.NewClient(httpClient, cfg.Server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jira client: %w", err)
+	}
+
+	pickJQL := cfg.PickJQL
+	if pickJQL == "" {
+		pickJQL = defaultPickJQL
+	}
+
+	return &JiraTracker{client: client, auth: cfg.Auth, pickJQL: pickJQL, transitions: cfg.Transitions}, nil
+}
+
+// jiraHTTPClient returns the http client authenticating requests with the configured method,
+// on top of the configured client transport if any
+func jiraHTTPClient(cfg c.JiraConfig) (*http.Client, error) {
+	var base http.RoundTripper
+	var timeout time.Duration
+	if cfg.HTTPClient != nil {
+		base, timeout = cfg.HTTPClient.Transport, cfg.HTTPClient.Timeout
+	}
+
+	switch cfg.Auth {
+	case "", c.JiraAuthBasic:
+		bt := &jira.BasicAuthTransport{Username: cfg.Username, Password: cfg.Password, Transport: base}
+		return &http.Client{Transport: bt, Timeout: timeout}, nil
+	case c.JiraAuthPAT:
+		if cfg.Token == "" {
+			return nil, errors.New("jira pat auth requires ticketing.jira.token")
+		}
+		pt := &jira.PATAuthTransport{Token: cfg.Token, Transport: base}
+		return &http.Client{Transport: pt, Timeout: timeout}, nil
+	case c.JiraAuthCloudToken:
+		if cfg.Username == "" || cfg.Token == "" {
+			return nil, errors.New("jira cloud_token auth requires ticketing.jira.username (email) and ticketing.jira.token")
+		}
+		bt := &jira.BasicAuthTransport{Username: cfg.Username, Password: cfg.Token, Transport: base}
+		return &http.Client{Transport: bt, Timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("unknown jira auth '%s' (basic, pat or cloud_token)", cfg.Auth)
+	}
+}
 
-	bt := jira.BasicAuthTransport{
-		Username: cfg.Username,
-		Password: cfg.Password,
+// Validate checks the credentials against the "myself" endpoint
+func (t *JiraTracker) Validate() error {
+	_, resp, err := t.client.User.GetSelf()
+	if err == nil {
+		return nil
 	}
-	jiraClient, _ = jira.NewClient(bt.Client(), cfg.Server)
+
+	if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+		auth := t.auth
+		if auth == "" {
+			auth = c.JiraAuthBasic
+		}
+		return fmt.Errorf("jira authentication failed (HTTP %d) with %s auth, check the ticketing.jira credentials", resp.StatusCode, auth)
+	}
+	return fmt.Errorf("jira %w - %w", ErrUnreachable, err)
+}
+
+// Name returns the tracker name
+func (t *JiraTracker) Name() string {
+	return c.JIRA
+}
+
+// ParseKey turns a Jira key into a Ticket, upper cased as Jira shows it (`proj-1` gives `PROJ-1`)
+func (t *JiraTracker) ParseKey(key string) (Ticket, error) {
+	return Ticket{Key: strings.ToUpper(strings.TrimSpace(key))}, nil
 }
 
-func GetJiraIssue(key string) *jira.Issue {
+// FetchTicket retrieves a Jira issue
+func (t *JiraTracker) FetchTicket(key string) (Ticket, error) {
+	parsed, _ := t.ParseKey(key)
+	key = parsed.Key
 
 	log.Debugf("key: %v\n", key)
 
-	issue, _, err := jiraClient.Issue.Get(key, nil)
+	issue, resp, err := t.client.Issue.Get(key, nil)
+	if err != nil {
+		return Ticket{}, fmt.Errorf("jira issue for key : %s - %w", key, unreachable(resp == nil, err))
+	}
+
+	ticket := Ticket{
+		Key:    key,
+		Title:  issue.Fields.Summary,
+		Type:   issue.Fields.Type.Name,
+		Labels: issue.Fields.Labels,
+	}
+	if issue.Fields.Status != nil {
+		ticket.Status = issue.Fields.Status.Name
+	}
+
+	return ticket, nil
+}
+
+// CreateTicket creates a Jira issue in the given project, a Task when no type is given
+func (t *JiraTracker) CreateTicket(nt NewTicket) (Ticket, error) {
+	if nt.Project == "" {
+		return Ticket{}, errors.New("a jira project is required to create a ticket (--project or ticketing.jira.project)")
+	}
+	if nt.Type == "" {
+		nt.Type = defaultTicketType
+	}
+
+	created, _, err := t.client.Issue.Create(&jira.Issue{
+		Fields: &jira.IssueFields{
+			Project:     jira.Project{Key: nt.Project},
+			Type:        jira.IssueType{Name: nt.Type},
+			Summary:     nt.Summary,
+			Description: nt.Description,
+		},
+	})
+	if err != nil {
+		return Ticket{}, fmt.Errorf("jira issue creation failed in project %s - %w", nt.Project, err)
+	}
+	log.Debugf("issue created: %v\n", created.Key)
+
+	return Ticket{Key: created.Key, Title: nt.Summary, Type: nt.Type}, nil
+}
+
+// WorkflowContext builds the branch and commit prefix from the configured templates
+func (t *JiraTracker) WorkflowContext(cfg c.Config, ticket Ticket, branchType, commitType string) (string, string) {
+	// Define branch template
+	branch := helper.Template(cfg.BranchTemplate, map[string]interface{}{
+		"type":    branchType,
+		"issue":   ticket.Key,
+		"summary": ticket.Title,
+	})
+	// Define commit template
+	commit := helper.Template(cfg.CommitTemplate, map[string]interface{}{
+		"type":  commitType,
+		"issue": ticket.Key,
+	})
+
+	return branch, commit
+}
+
+// Refs returns the Jira ticket reference of a workflow
+func (t *JiraTracker) Refs(wf c.Workflow) []c.TicketRef {
+	return []c.TicketRef{
+		{Label: "ticket", Param: helper.TICKETPARAM, Value: wf.Ticket},
+	}
+}
+
+// ListAssigned returns the Jira issues matching the pick JQL
+func (t *JiraTracker) ListAssigned() ([]Ticket, error) {
+	log.Debugf("jql: %v\n", t.pickJQL)
+
+	issues, _, err := t.client.Issue.Search(t.pickJQL, &jira.SearchOptions{
+		MaxResults: 50,
+		Fields:     []string{"summary", "issuetype", "status", "labels"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("jira search failed for jql '%s' - %w", t.pickJQL, err)
+	}
+
+	var tickets []Ticket
+	for _, issue := range issues {
+		ticket := Ticket{Key: issue.Key}
+		if issue.Fields != nil {
+			ticket.Title = issue.Fields.Summary
+			ticket.Type = issue.Fields.Type.Name
+			ticket.Labels = issue.Fields.Labels
+			if issue.Fields.Status != nil {
+				ticket.Status = issue.Fields.Status.Name
+			}
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	return tickets, nil
+}
+
+// TransitionName returns the transition configured for the event, for the ticket project or by default
+func (t *JiraTracker) TransitionName(key, event string) string {
+	project := strings.ToLower(strings.SplitN(key, "-", 2)[0])
+	if name, ok := t.transitions[project][event]; ok {
+		return name
+	}
+	return t.transitions[defaultTransitions][event]
+}
+
+// Transition applies the transition matching the name (transition or target status) on a Jira issue
+func (t *JiraTracker) Transition(key, name string) error {
+	issue, _, err := t.client.Issue.Get(key, &jira.GetQueryOptions{Fields: "status"})
+	if err != nil {
+		return fmt.Errorf("jira issue for key : %s - %w", key, err)
+	}
+	current := ""
+	if issue.Fields != nil && issue.Fields.Status != nil {
+		current = issue.Fields.Status.Name
+	}
+	if strings.EqualFold(current, name) {
+		log.Debugln("Jira issue " + key + " already in status " + current)
+		return nil
+	}
+
+	transitions, _, err := t.client.Issue.GetTransitions(key)
 	if err != nil {
-		helper.SpinStopDisplay("fail")
-		log.Fatalln("Jira issue for key : " + key + " - " + err.Error())
+		return fmt.Errorf("jira transitions for key : %s - %w", key, err)
+	}
+
+	var available []string
+	for _, tr := range transitions {
+		if strings.EqualFold(tr.Name, name) || strings.EqualFold(tr.To.Name, name) {
+			log.Debugf("Jira transition %v (%v) -> %v\n", tr.Name, tr.ID, tr.To.Name)
+			if _, err := t.client.Issue.DoTransition(key, tr.ID); err != nil {
+				return fmt.Errorf("jira transition '%s' for key : %s - %w", tr.Name, key, err)
+			}
+			return nil
+		}
+		available = append(available, tr.Name+" -> "+tr.To.Name)
+	}
+
+	return fmt.Errorf("transition '%s' is not available from status '%s' (available: %s)", name, current, strings.Join(available, ", "))
+}
+
+// AddWorklog records time spent on a Jira issue
+func (t *JiraTracker) AddWorklog(key string, started time.Time, spent time.Duration) error {
+	jiraStarted := jira.Time(started)
+	record := &jira.WorklogRecord{
+		Started:          &jiraStarted,
+		TimeSpentSeconds: int(spent.Seconds()),
+	}
+
+	if _, _, err := t.client.Issue.AddWorklogRecord(key, record); err != nil {
+		return fmt.Errorf("jira worklog for key : %s - %w", key, err)
+	}
+	return nil
+}
+
+// CommentCommits posts a comment listing the pushed commits on a Jira issue
+func (t *JiraTracker) CommentCommits(key, branch, browserURL string, commits []c.CommitInfo) error {
+	comment := &jira.Comment{Body: jiraCommitsComment(branch, browserURL, commits)}
+	if _, _, err := t.client.Issue.AddComment(key, comment); err != nil {
+		return fmt.Errorf("jira comment for key : %s - %w", key, err)
+	}
+	return nil
+}
+
+// jiraCommitsComment renders the pushed commits in Jira wiki markup, one linked short hash per commit
+func jiraCommitsComment(branch, browserURL string, commits []c.CommitInfo) string {
+	lines := []string{fmt.Sprintf("Pushed %d commit(s) to {{%s}}:", len(commits), branch)}
+	for _, commit := range commits {
+		short := commit.Hash
+		if len(short) > 7 {
+			short = short[:7]
+		}
+		lines = append(lines, fmt.Sprintf("* [%s|%s/commit/%s] %s", short, browserURL, commit.Hash, commit.Subject))
 	}
 
-	return issue
+	return strings.Join(lines, "\n")
 }
@@ -0,0 +1,413 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package ticketing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newJiraServer starts an httptest stand-in of the Jira REST API, the issue being in the given status.
+// Applied transition ids are recorded in done.
+func newJiraServer(t *testing.T, status string, done *[]string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/issue/PROJ-1", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"key": "PROJ-1",
+			"fields": map[string]interface{}{
+				"summary":   "Fix login",
+				"issuetype": map[string]string{"name": "Bug"},
+				"status":    map[string]string{"name": status},
+				"labels":    []string{"frontend"},
+			},
+		})
+	})
+	mux.HandleFunc("/rest/api/2/issue/PROJ-1/transitions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var payload struct {
+				Transition struct {
+					ID string `json:"id"`
+				} `json:"transition"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			*done = append(*done, payload.Transition.ID)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"transitions": []map[string]interface{}{
+				{"id": "11", "name": "Start Progress", "to": map[string]string{"name": "In Progress"}},
+				{"id": "21", "name": "Review", "to": map[string]string{"name": "In Review"}},
+			},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestJiraTracker_FetchTicket(t *testing.T) {
+	var done []string
+	server := newJiraServer(t, "To Do", &done)
+	tracker, _ := NewJiraTracker(c.JiraConfig{Server: server.URL})
+
+	ticket, err := tracker.FetchTicket("PROJ-1")
+	if err != nil {
+		t.Fatalf("FetchTicket() unexpected error: %v", err)
+	}
+	if ticket.Title != "Fix login" || ticket.Type != "Bug" || ticket.Status != "To Do" {
+		t.Errorf("FetchTicket() = %+v", ticket)
+	}
+	if len(ticket.Labels) != 1 || ticket.Labels[0] != "frontend" {
+		t.Errorf("Labels = %v, want [frontend]", ticket.Labels)
+	}
+
+	// Keys are normalised as Jira shows them
+	ticket, err = tracker.FetchTicket(" proj-1")
+	if err != nil {
+		t.Fatalf("FetchTicket() lower case key unexpected error: %v", err)
+	}
+	if ticket.Key != "PROJ-1" {
+		t.Errorf("Key = %v, want PROJ-1", ticket.Key)
+	}
+}
+
+func TestJiraTracker_TransitionName(t *testing.T) {
+	tracker, _ := NewJiraTracker(c.JiraConfig{
+		Server: "https://jira.example.com",
+		Transitions: map[string]map[string]string{
+			"default": {c.EventInit: "In Progress", c.EventEnd: "In Review"},
+			"ops":     {c.EventEnd: "Done", c.EventInit: ""},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		key      string
+		event    string
+		expected string
+	}{
+		{name: "default init", key: "PROJ-1", event: c.EventInit, expected: "In Progress"},
+		{name: "default end", key: "PROJ-1", event: c.EventEnd, expected: "In Review"},
+		{name: "not configured", key: "PROJ-1", event: c.EventPause, expected: ""},
+		{name: "project override", key: "OPS-2", event: c.EventEnd, expected: "Done"},
+		{name: "project disables transition", key: "OPS-2", event: c.EventInit, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tracker.TransitionName(tt.key, tt.event)
+			if result != tt.expected {
+				t.Errorf("TransitionName() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestJiraTracker_Transition(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       string
+		transition   string
+		expectedDone []string
+		wantErr      bool
+	}{
+		{
+			name:         "by target status",
+			status:       "To Do",
+			transition:   "In Progress",
+			expectedDone: []string{"11"},
+		},
+		{
+			name:         "by transition name",
+			status:       "In Progress",
+			transition:   "review",
+			expectedDone: []string{"21"},
+		},
+		{
+			name:       "already in status",
+			status:     "In Progress",
+			transition: "In Progress",
+		},
+		{
+			name:       "not available",
+			status:     "To Do",
+			transition: "Done",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var done []string
+			server := newJiraServer(t, tt.status, &done)
+			tracker, _ := NewJiraTracker(c.JiraConfig{Server: server.URL})
+
+			err := tracker.Transition("PROJ-1", tt.transition)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Transition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "Start Progress -> In Progress") {
+				t.Errorf("Transition() error should list available transitions, got: %v", err)
+			}
+			if strings.Join(done, ",") != strings.Join(tt.expectedDone, ",") {
+				t.Errorf("applied transitions = %v, want %v", done, tt.expectedDone)
+			}
+		})
+	}
+}
+
+func TestJiraTracker_AddWorklog(t *testing.T) {
+	var spent int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/issue/PROJ-1/worklog", func(w http.ResponseWriter, r *http.Request) {
+		var record struct {
+			TimeSpentSeconds int `json:"timeSpentSeconds"`
+		}
+		json.NewDecoder(r.Body).Decode(&record)
+		spent = record.TimeSpentSeconds
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "100", "timeSpentSeconds": spent})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tracker, _ := NewJiraTracker(c.JiraConfig{Server: server.URL})
+
+	if err := tracker.AddWorklog("PROJ-1", time.Now().Add(-time.Hour), 45*time.Minute); err != nil {
+		t.Fatalf("AddWorklog() unexpected error: %v", err)
+	}
+	if spent != 2700 {
+		t.Errorf("timeSpentSeconds = %v, want 2700", spent)
+	}
+
+	if err := tracker.AddWorklog("PROJ-2", time.Now(), time.Minute); err == nil {
+		t.Error("AddWorklog() expected error for unknown issue")
+	}
+}
+
+func TestJiraCommitsComment(t *testing.T) {
+	commits := []c.CommitInfo{
+		{Hash: "0123456789abcdef", Subject: "fix(PROJ-1): handle empty password"},
+		{Hash: "fedcba9876543210", Subject: "fix(PROJ-1): trim login"},
+	}
+
+	expected := "Pushed 2 commit(s) to {{fix/PROJ-1_login}}:\n" +
+		"* [0123456|https://gitlab.example.com/team/app/commit/0123456789abcdef] fix(PROJ-1): handle empty password\n" +
+		"* [fedcba9|https://gitlab.example.com/team/app/commit/fedcba9876543210] fix(PROJ-1): trim login"
+
+	result := jiraCommitsComment("fix/PROJ-1_login", "https://gitlab.example.com/team/app", commits)
+	if result != expected {
+		t.Errorf("jiraCommitsComment() = %q, want %q", result, expected)
+	}
+}
+
+func TestJiraTracker_CommentCommits(t *testing.T) {
+	var body string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/issue/PROJ-1/comment", func(w http.ResponseWriter, r *http.Request) {
+		var comment struct {
+			Body string `json:"body"`
+		}
+		json.NewDecoder(r.Body).Decode(&comment)
+		body = comment.Body
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "200", "body": body})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tracker, _ := NewJiraTracker(c.JiraConfig{Server: server.URL})
+
+	err := tracker.CommentCommits("PROJ-1", "feat/PROJ-1", "https://example.com/app", []c.CommitInfo{{Hash: "abc", Subject: "feat: login"}})
+	if err != nil {
+		t.Fatalf("CommentCommits() unexpected error: %v", err)
+	}
+	if !strings.Contains(body, "[abc|https://example.com/app/commit/abc] feat: login") {
+		t.Errorf("comment body = %q", body)
+	}
+
+	if err := tracker.CommentCommits("PROJ-2", "feat/PROJ-2", "", nil); err == nil {
+		t.Error("CommentCommits() expected error for unknown issue")
+	}
+}
+
+func TestJiraTracker_Validate(t *testing.T) {
+	// The stand-in server accepts the password "secret", the api token "api-token" and the pat "pat-token"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/myself", func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		validBasic := ok && username != "" && (password == "secret" || password == "api-token")
+		if !validBasic && r.Header.Get("Authorization") != "Bearer pat-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"name": "me"})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name       string
+		cfg        c.JiraConfig
+		wantNewErr bool
+		wantErr    bool
+	}{
+		{
+			name: "basic",
+			cfg:  c.JiraConfig{Username: "me", Password: "secret"},
+		},
+		{
+			name: "default auth is basic",
+			cfg:  c.JiraConfig{Auth: "", Username: "me", Password: "secret"},
+		},
+		{
+			name: "personal access token",
+			cfg:  c.JiraConfig{Auth: c.JiraAuthPAT, Token: "pat-token"},
+		},
+		{
+			name: "cloud api token",
+			cfg:  c.JiraConfig{Auth: c.JiraAuthCloudToken, Username: "me@example.com", Token: "api-token"},
+		},
+		{
+			name:    "wrong password",
+			cfg:     c.JiraConfig{Username: "me", Password: "wrong"},
+			wantErr: true,
+		},
+		{
+			name:       "pat without token",
+			cfg:        c.JiraConfig{Auth: c.JiraAuthPAT},
+			wantNewErr: true,
+		},
+		{
+			name:       "cloud token without email",
+			cfg:        c.JiraConfig{Auth: c.JiraAuthCloudToken, Token: "api-token"},
+			wantNewErr: true,
+		},
+		{
+			name:       "unknown auth",
+			cfg:        c.JiraConfig{Auth: "oauth"},
+			wantNewErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Server = server.URL
+			tracker, err := NewJiraTracker(tt.cfg)
+			if (err != nil) != tt.wantNewErr {
+				t.Fatalf("NewJiraTracker() error = %v, wantErr %v", err, tt.wantNewErr)
+			}
+			if tt.wantNewErr {
+				return
+			}
+
+			err = tracker.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "authentication failed") {
+				t.Errorf("Validate() error should report the authentication failure, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestJiraTracker_ValidateUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	tracker, _ := NewJiraTracker(c.JiraConfig{Server: server.URL, Username: "me", Password: "secret"})
+	if err := tracker.Validate(); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Validate() error = %v, want ErrUnreachable", err)
+	}
+}
+
+func TestJiraTracker_CreateTicket(t *testing.T) {
+	var fields map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/issue", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Fields map[string]interface{} `json:"fields"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		fields = payload.Fields
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"id": "10001", "key": "PROJ-7"})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tracker, _ := NewJiraTracker(c.JiraConfig{Server: server.URL})
+
+	if _, err := tracker.CreateTicket(NewTicket{Summary: "Fix logout"}); err == nil {
+		t.Error("CreateTicket() expected error without project")
+	}
+
+	ticket, err := tracker.CreateTicket(NewTicket{Project: "PROJ", Summary: "Fix logout", Description: "Session kept"})
+	if err != nil {
+		t.Fatalf("CreateTicket() unexpected error: %v", err)
+	}
+	if ticket.Key != "PROJ-7" || ticket.Title != "Fix logout" || ticket.Type != "Task" {
+		t.Errorf("CreateTicket() = %+v, want PROJ-7 Task", ticket)
+	}
+	if fields["summary"] != "Fix logout" || fields["description"] != "Session kept" {
+		t.Errorf("fields = %v", fields)
+	}
+	if project, _ := fields["project"].(map[string]interface{}); project["key"] != "PROJ" {
+		t.Errorf("project = %v, want PROJ", fields["project"])
+	}
+	if issuetype, _ := fields["issuetype"].(map[string]interface{}); issuetype["name"] != "Task" {
+		t.Errorf("issuetype = %v, want Task", fields["issuetype"])
+	}
+}
+
+func TestJiraTracker_ListAssigned(t *testing.T) {
+	var jql string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/search", func(w http.ResponseWriter, r *http.Request) {
+		jql = r.URL.Query().Get("jql")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issues": []map[string]interface{}{
+				{
+					"key": "PROJ-1",
+					"fields": map[string]interface{}{
+						"summary":   "Fix login",
+						"issuetype": map[string]string{"name": "Bug"},
+						"status":    map[string]string{"name": "To Do"},
+					},
+				},
+			},
+		})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tracker, _ := NewJiraTracker(c.JiraConfig{Server: server.URL})
+	tickets, err := tracker.ListAssigned()
+	if err != nil {
+		t.Fatalf("ListAssigned() unexpected error: %v", err)
+	}
+	if jql != defaultPickJQL {
+		t.Errorf("jql = %v, want %v", jql, defaultPickJQL)
+	}
+	if len(tickets) != 1 || tickets[0].Key != "PROJ-1" || tickets[0].Title != "Fix login" || tickets[0].Status != "To Do" {
+		t.Errorf("ListAssigned() = %+v", tickets)
+	}
+
+	tracker, _ = NewJiraTracker(c.JiraConfig{Server: server.URL, PickJQL: "project = PROJ"})
+	tracker.ListAssigned()
+	if jql != "project = PROJ" {
+		t.Errorf("jql = %v, want project = PROJ", jql)
+	}
+}
@@ -0,0 +1,219 @@
+package ticketing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// redacted replaces the secrets in the recorded interactions
+	redacted = "REDACTED"
+)
+
+var (
+	// unrecordedHeaders are the response headers never written in a cassette
+	unrecordedHeaders = []string{"Set-Cookie", "Authorization", "Private-Token", "Www-Authenticate"}
+)
+
+// Cassette holds HTTP interactions recorded against a tracker, to be replayed in tests
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response. Request headers are not recorded,
+// they carry the credentials.
+type Interaction struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper writing every exchange in a cassette file, secrets being scrubbed.
+// The cassette is saved after each exchange, so a command failing midway is still recorded.
+type Recorder struct {
+	path      string
+	transport http.RoundTripper
+	secrets   []string
+	mu        sync.Mutex
+	cassette  Cassette
+}
+
+// NewRecorder creates a recorder sending the requests through transport (the default one when nil)
+// and writing them to path, replacing the given secrets
+func NewRecorder(path string, transport http.RoundTripper, secrets []string) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	var kept []string
+	for _, secret := range secrets {
+		if secret != "" {
+			kept = append(kept, secret)
+		}
+	}
+
+	return &Recorder{path: path, transport: transport, secrets: kept}
+}
+
+// RoundTrip sends the request and records it with its response
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		requestBody = body
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	for _, name := range unrecordedHeaders {
+		header.Del(name)
+	}
+	for name, values := range header {
+		for i, value := range values {
+			header[name][i] = r.scrub(value)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Method:      req.Method,
+		URL:         r.scrub(req.URL.String()),
+		RequestBody: r.scrub(string(requestBody)),
+		Status:      resp.StatusCode,
+		Header:      header,
+		Body:        r.scrub(string(body)),
+	})
+	if err := r.save(); err != nil {
+		log.Warningln("HTTP exchange not recorded: " + err.Error())
+	}
+
+	return resp, nil
+}
+
+// scrub replaces the secrets, as is or url encoded, found in s
+func (r *Recorder) scrub(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+		s = strings.ReplaceAll(s, url.QueryEscape(secret), redacted)
+	}
+	return s
+}
+
+// save writes the cassette, readable by the user only
+func (r *Recorder) save() error {
+	content, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, content, 0600)
+}
+
+// Replayer is an http.RoundTripper answering requests from a cassette, without network access.
+// Requests match interactions on method, path and query, whatever the server, in the recorded order.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer loads the cassette recorded at path
+func NewReplayer(path string) (*Replayer, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette not read: %w", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(content, &cassette); err != nil {
+		return nil, fmt.Errorf("cassette %s not parsed: %w", path, err)
+	}
+
+	return &Replayer{interactions: cassette.Interactions, used: make([]bool, len(cassette.Interactions))}, nil
+}
+
+// Client returns an http client replaying the cassette
+func (r *Replayer) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip answers the request with the first unused matching interaction,
+// the last matching one being replayed again once all are used
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := -1
+	for i, interaction := range r.interactions {
+		if !sameRequest(interaction, req) {
+			continue
+		}
+		found = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if found < 0 {
+		return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, req.URL)
+	}
+	r.used[found] = true
+
+	interaction := r.interactions[found]
+	header := interaction.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(interaction.Body)),
+		ContentLength: int64(len(interaction.Body)),
+		Request:       req,
+	}, nil
+}
+
+// sameRequest tells whether the interaction was recorded for the request method, path and query
+func sameRequest(interaction Interaction, req *http.Request) bool {
+	if interaction.Method != req.Method {
+		return false
+	}
+	recorded, err := url.Parse(interaction.URL)
+	if err != nil {
+		return false
+	}
+	return recorded.Path == req.URL.Path && recorded.Query().Encode() == req.URL.Query().Encode()
+}
@@ -0,0 +1,129 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package ticketing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/owner/repo/issues/7", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"number":   7,
+			"title":    "Broken pagination",
+			"state":    "open",
+			"body":     "seen with " + r.Header.Get("Authorization"),
+			"html_url": "https://gitea.example.com/owner/repo/issues/7",
+		})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	path := filepath.Join(t.TempDir(), "cassettes", "gitea.json")
+	recorder := NewRecorder(path, nil, []string{"gitea-s3cret", ""})
+	tracker, _ := NewGiteaTracker(c.GiteaConfig{
+		Server:     server.URL,
+		Token:      "gitea-s3cret",
+		HTTPClient: &http.Client{Transport: recorder},
+	}, "owner", "repo")
+	if _, err := tracker.FetchTicket("7"); err != nil {
+		t.Fatalf("FetchTicket() recording unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cassette not written: %v", err)
+	}
+	if strings.Contains(string(content), "gitea-s3cret") || strings.Contains(string(content), "session") {
+		t.Errorf("cassette leaks secrets:\n%s", content)
+	}
+	if !strings.Contains(string(content), "seen with token REDACTED") {
+		t.Errorf("cassette secret not scrubbed:\n%s", content)
+	}
+
+	// The cassette replays against any server, without network
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatalf("NewReplayer() unexpected error: %v", err)
+	}
+	replayed, _ := NewGiteaTracker(c.GiteaConfig{Server: "https://gitea.example.com", HTTPClient: replayer.Client()}, "owner", "repo")
+	ticket, err := replayed.FetchTicket("7")
+	if err != nil {
+		t.Fatalf("FetchTicket() replay unexpected error: %v", err)
+	}
+	if ticket.Title != "Broken pagination" {
+		t.Errorf("Title = %v, want Broken pagination", ticket.Title)
+	}
+	if _, err := replayed.FetchTicket("8"); err == nil {
+		t.Error("FetchTicket() expected error for an unrecorded request")
+	}
+}
+
+func TestReplayer_JiraFetchTicket(t *testing.T) {
+	replayer, err := NewReplayer(filepath.Join("testdata", "jira_fetch_ticket.json"))
+	if err != nil {
+		t.Fatalf("NewReplayer() unexpected error: %v", err)
+	}
+	tracker, err := NewJiraTracker(c.JiraConfig{
+		Server:     "https://jira.example.com",
+		Username:   "user",
+		Password:   "pass",
+		HTTPClient: replayer.Client(),
+	})
+	if err != nil {
+		t.Fatalf("NewJiraTracker() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		key           string
+		expectedTitle string
+		expectedType  string
+		wantErr       bool
+	}{
+		{
+			name:          "issue",
+			key:           "PROJ-123",
+			expectedTitle: "Export fails for large reports",
+			expectedType:  "Bug",
+		},
+		{
+			name:    "not found",
+			key:     "PROJ-404",
+			wantErr: true,
+		},
+		{
+			name:    "not recorded",
+			key:     "PROJ-999",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticket, err := tracker.FetchTicket(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchTicket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if ticket.Title != tt.expectedTitle {
+				t.Errorf("Title = %v, want %v", ticket.Title, tt.expectedTitle)
+			}
+			if ticket.Type != tt.expectedType {
+				t.Errorf("Type = %v, want %v", ticket.Type, tt.expectedType)
+			}
+		})
+	}
+}
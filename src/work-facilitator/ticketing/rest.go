@@ -0,0 +1,53 @@
+package ticketing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// doJSON sends a REST API request and decodes the JSON response into v
+func doJSON(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w - %w", ErrUnreachable, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errResp apiErrorResponse
+		if err := json.Unmarshal(body, &errResp); err == nil && errResp.Message != "" {
+			return fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Message)
+		}
+		return fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+	}
+
+	if v == nil || len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
+
+// apiErrorResponse is the error body shared by GitHub and Gitea APIs
+type apiErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// restClient returns the configured http client, or a default one with a timeout
+func restClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
@@ -0,0 +1,225 @@
+package ticketing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"time"
+)
+
+// Tracker defines the interface a ticketing backend implements to drive workflows
+type Tracker interface {
+	// Name returns the tracker name, as used in the configuration
+	Name() string
+
+	// ParseKey turns a ticket reference given on the command line into a Ticket,
+	// without contacting the tracker
+	ParseKey(key string) (Ticket, error)
+
+	// FetchTicket retrieves a ticket from the tracker
+	FetchTicket(key string) (Ticket, error)
+
+	// WorkflowContext returns the branch name and the commit prefix of a workflow
+	// started from the given ticket
+	WorkflowContext(cfg c.Config, ticket Ticket, branchType, commitType string) (string, string)
+
+	// Refs returns the ticket references stored in the workflow config
+	// and rendered in the workflow summary
+	Refs(wf c.Workflow) []c.TicketRef
+}
+
+// Validator is implemented by trackers able to check their credentials up front
+type Validator interface {
+	// Validate returns a readable error when the tracker cannot be used
+	Validate() error
+}
+
+// Transitioner is implemented by trackers able to move tickets on workflow lifecycle events
+type Transitioner interface {
+	// TransitionName returns the transition configured for the lifecycle event, empty when none
+	TransitionName(key, event string) string
+
+	// Transition applies the named transition (or moves to the named status) on the ticket
+	Transition(key, name string) error
+}
+
+// Worklogger is implemented by trackers able to record time spent on tickets
+type Worklogger interface {
+	// AddWorklog records the time spent on the ticket, started at the given time
+	AddWorklog(key string, started time.Time, spent time.Duration) error
+}
+
+// Commenter is implemented by trackers able to report pushed commits on tickets
+type Commenter interface {
+	// CommentCommits posts a comment listing the commits pushed on the branch,
+	// linked from the repository browser url
+	CommentCommits(key, branch, browserURL string, commits []c.CommitInfo) error
+}
+
+// Creator is implemented by trackers able to create tickets
+type Creator interface {
+	// CreateTicket creates a ticket and returns it as fetched
+	CreateTicket(t NewTicket) (Ticket, error)
+}
+
+// Lister is implemented by trackers able to list the open tickets assigned to the user
+type Lister interface {
+	// ListAssigned returns the open tickets assigned to the authenticated user
+	ListAssigned() ([]Ticket, error)
+}
+
+// IssueFetcher is implemented by trackers whose issues are distinct from merge requests
+type IssueFetcher interface {
+	// FetchIssue retrieves an issue from the tracker, IssueID being set on the returned Ticket
+	FetchIssue(key string) (Ticket, error)
+}
+
+// MergeRequester is implemented by trackers able to open merge requests for new workflows
+type MergeRequester interface {
+	// CreateMergeRequest opens a merge request and returns it as a Ticket
+	CreateMergeRequest(mr MergeRequest) (Ticket, error)
+}
+
+// MergeRequestFinder is implemented by trackers whose tickets and merge requests live apart
+type MergeRequestFinder interface {
+	// FindMergeRequest returns the open merge request of the branch, a zero Ticket when there is none
+	FindMergeRequest(branch string) (Ticket, error)
+}
+
+// MergeChecker is implemented by trackers able to tell whether a merge request is merged
+type MergeChecker interface {
+	// MergeState returns the state of the merge request with the given number
+	MergeState(id int) (MergeState, error)
+}
+
+// ReadyMarker is implemented by trackers able to hand a merge request over to review
+type ReadyMarker interface {
+	// MarkReady removes the draft flag of the merge request
+	MarkReady(id int) error
+
+	// AssignReviewers requests a review from the users, returning the usernames that could not be resolved
+	AssignReviewers(id int, usernames []string) ([]string, error)
+
+	// AddLabels adds the labels to the merge request
+	AddLabels(id int, labels []string) error
+
+	// CommentMergeRequest posts a comment on the merge request
+	CommentMergeRequest(id int, body string) error
+}
+
+// PipelineReporter is implemented by trackers able to report the CI pipeline of a branch
+type PipelineReporter interface {
+	// LatestPipeline returns the latest pipeline of the branch, a zero Pipeline when there is none
+	LatestPipeline(branch string) (c.Pipeline, error)
+}
+
+// ReviewReporter is implemented by trackers able to report the review state of a merge request
+type ReviewReporter interface {
+	// Review returns the review state of the merge request with the given number
+	Review(id int) (c.Review, error)
+}
+
+// MergeState holds the state of a merge request
+type MergeState struct {
+	State       string
+	Merged      bool
+	MergeCommit string
+}
+
+// NewTicket holds the data of a ticket to create
+type NewTicket struct {
+	// Project is the project key, for trackers hosting several projects (e.g. Jira)
+	Project     string
+	Type        string
+	Summary     string
+	Description string
+}
+
+// MergeRequest holds the data of a merge request to open
+type MergeRequest struct {
+	Title        string
+	Description  string
+	SourceBranch string
+	TargetBranch string
+	Labels       []string
+	Reviewers    []string
+	Draft        bool
+}
+
+// Ticket holds the tracker data a workflow is built from
+type Ticket struct {
+	// Key is the ticket reference as given by the user (e.g. PROJ-123, 42)
+	Key string
+
+	// ID is the numeric reference, for trackers using numbers (MR, PR, issues)
+	ID int
+
+	// IssueID is the issue the workflow resolves, for trackers distinguishing issues from merge requests
+	IssueID int
+
+	// Title is the ticket summary
+	Title string
+
+	// Type is the ticket type (e.g. Bug, Story)
+	Type string
+
+	// Labels are the ticket labels
+	Labels []string
+
+	// Status is the current ticket status
+	Status string
+
+	// Branch is the source branch, when the tracker knows it (MR, PR)
+	Branch string
+}
+
+// Factory builds a Tracker from the workflow config and the current repository,
+// its API calls going through client when not nil
+type Factory func(cfg c.Config, repo c.Repo, client *http.Client) (Tracker, error)
+
+var (
+	registry = map[string]Factory{}
+
+	// ErrUnreachable reports a tracker that could not be contacted, as opposed to a rejected request
+	ErrUnreachable = errors.New("server not reachable")
+)
+
+// unreachable wraps err in ErrUnreachable when the tracker gave no response
+func unreachable(noResponse bool, err error) error {
+	if noResponse {
+		return fmt.Errorf("%w - %w", ErrUnreachable, err)
+	}
+	return err
+}
+
+// Register makes a tracker available under the given ticketing name
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+// Registered returns the sorted list of registered tracker names
+func Registered() []string {
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds the tracker matching the configured ticketing system, client replacing
+// the default HTTP client when not nil (e.g. to record or replay the API exchanges)
+func New(cfg c.Config, repo c.Repo, client *http.Client) (Tracker, error) {
+	if cfg.Ticketing == "" {
+		return nil, errors.New("no ticketing system enabled")
+	}
+
+	factory, ok := registry[cfg.Ticketing]
+	if !ok {
+		return nil, fmt.Errorf("unknown ticketing system '%s'", cfg.Ticketing)
+	}
+
+	return factory(cfg, repo, client)
+}
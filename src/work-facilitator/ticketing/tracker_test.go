@@ -0,0 +1,149 @@
+/*
+Copyright © 2024 Jean Bordat bordat.jean@gmail.com
+*/
+package ticketing
+
+import (
+	c "spirit-dev/work-facilitator/work-facilitator/common"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		ticketing string
+		wantName  string
+		wantErr   bool
+	}{
+		{
+			name:      "jira",
+			ticketing: c.JIRA,
+			wantName:  c.JIRA,
+		},
+		{
+			name:      "gitlab",
+			ticketing: c.GITLAB,
+			wantName:  c.GITLAB,
+		},
+		{
+			name:      "no ticketing",
+			ticketing: "",
+			wantErr:   true,
+		},
+		{
+			name:      "unknown ticketing",
+			ticketing: "REDMINE",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, err := New(c.Config{Ticketing: tt.ticketing}, c.Repo{FName: "ns/repo"}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tracker.Name() != tt.wantName {
+				t.Errorf("Name() = %v, want %v", tracker.Name(), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	registered := Registered()
+	for _, name := range c.Trackers {
+		found := false
+		for _, r := range registered {
+			if r == name {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Tracker %v is not registered", name)
+		}
+	}
+}
+
+func TestGitlabTracker_ParseKey(t *testing.T) {
+	tracker, _ := NewGitlabTracker(c.GlabConfig{BaseUrl: "https://gitlab.example.com"}, "ns/repo")
+
+	ticket, err := tracker.ParseKey("42")
+	if err != nil {
+		t.Fatalf("ParseKey() unexpected error: %v", err)
+	}
+	if ticket.ID != 42 || ticket.Key != "42" {
+		t.Errorf("ParseKey() = %+v, want ID 42 and Key 42", ticket)
+	}
+
+	if _, err := tracker.ParseKey("PROJ-42"); err == nil {
+		t.Error("ParseKey() expected error for non numeric key")
+	}
+}
+
+func TestWorkflowContext(t *testing.T) {
+	cfg := c.Config{
+		BranchTemplate: "{{type}}/{{issue}}_{{summary}}",
+		CommitTemplate: "{{type}}: {{issue}} ",
+	}
+	jiraTracker, _ := NewJiraTracker(c.JiraConfig{Server: "https://jira.example.com"})
+	glabTracker, _ := NewGitlabTracker(c.GlabConfig{BaseUrl: "https://gitlab.example.com"}, "ns/repo")
+
+	tests := []struct {
+		name           string
+		tracker        Tracker
+		ticket         Ticket
+		expectedBranch string
+		expectedCommit string
+	}{
+		{
+			name:           "jira uses templates",
+			tracker:        jiraTracker,
+			ticket:         Ticket{Key: "PROJ-123", Title: "fix_login"},
+			expectedBranch: "feature/PROJ-123_fix_login",
+			expectedCommit: "feat: PROJ-123 ",
+		},
+		{
+			name:           "gitlab uses title without source branch",
+			tracker:        glabTracker,
+			ticket:         Ticket{Key: "42", ID: 42, Title: "fix_login"},
+			expectedBranch: "fix_login",
+			expectedCommit: "feat(!42): ",
+		},
+		{
+			name:           "gitlab uses mr source branch",
+			tracker:        glabTracker,
+			ticket:         Ticket{Key: "42", ID: 42, Title: "fix_login", Branch: "42-fix-login"},
+			expectedBranch: "42-fix-login",
+			expectedCommit: "feat(!42): ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			branch, commit := tt.tracker.WorkflowContext(cfg, tt.ticket, "feature", "feat")
+			if branch != tt.expectedBranch {
+				t.Errorf("branch = %v, want %v", branch, tt.expectedBranch)
+			}
+			if commit != tt.expectedCommit {
+				t.Errorf("commit = %v, want %v", commit, tt.expectedCommit)
+			}
+		})
+	}
+}
+
+func TestRefs(t *testing.T) {
+	jiraTracker, _ := NewJiraTracker(c.JiraConfig{Server: "https://jira.example.com"})
+	glabTracker, _ := NewGitlabTracker(c.GlabConfig{BaseUrl: "https://gitlab.example.com"}, "ns/repo")
+	wf := c.Workflow{Issue: 42, Ticket: "PROJ-123"}
+
+	jiraRefs := jiraTracker.Refs(wf)
+	if len(jiraRefs) != 1 || jiraRefs[0].Param != "ticket" || jiraRefs[0].Value != "PROJ-123" {
+		t.Errorf("Jira Refs() = %+v", jiraRefs)
+	}
+
+	glabRefs := glabTracker.Refs(wf)
+	if len(glabRefs) != 1 || glabRefs[0].Param != "mrref" || glabRefs[0].Value != "42" {
+		t.Errorf("GitLab Refs() = %+v", glabRefs)
+	}
+}